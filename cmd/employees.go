@@ -0,0 +1,179 @@
+package cmd
+
+import (
+	"fmt"
+	"math/big"
+
+	"bvorhofer.com/matchmaker/gnucash"
+	"github.com/spf13/cobra"
+)
+
+var (
+	employeesCmd = &cobra.Command{
+		Use:   "employees",
+		Short: "List, add and edit employees",
+		Long: `List, add and edit employees (e.g. flatmates or colleagues) monthly can split bill
+entries to via expense vouchers.`,
+	}
+
+	employeesListCmd = &cobra.Command{
+		Use:   "list FILE",
+		Short: "List employees",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			book, err := gnucash.OpenBookFromSQLite(args[0],
+				gnucash.LoadTables(gnucash.TableSlots|gnucash.TableEmployees))
+			if err != nil {
+				fatal(err)
+			}
+			defer book.Close()
+
+			for _, e := range book.GetEmployees() {
+				if e.Active == 0 && !employeesAll {
+					continue
+				}
+
+				printEmployee(e)
+			}
+		},
+	}
+
+	employeesAddCmd = &cobra.Command{
+		Use:   "add FILE USERNAME",
+		Short: "Add an employee",
+		Args:  cobra.ExactArgs(2),
+		Run: func(cmd *cobra.Command, args []string) {
+			book, err := gnucash.OpenBookFromSQLite(args[0],
+				gnucash.LoadTables(gnucash.TableSlots|gnucash.TableEmployees))
+			if err != nil {
+				fatal(err)
+			}
+			defer book.Close()
+			lockBook(book)
+
+			if book.GetEmployeeByUsername(args[1]) != nil {
+				fatalf("Employee '%s' already exists\n", args[1])
+			}
+
+			e := &gnucash.Employee{
+				DbEmployee: gnucash.DbEmployee{
+					Username: args[1],
+					Active:   1,
+				},
+			}
+			applyEmployeeFlags(cmd, book, e)
+
+			if err := book.AddEmployee(e); err != nil {
+				fatal(err)
+			}
+			printEmployee(e)
+		},
+	}
+
+	employeesEditCmd = &cobra.Command{
+		Use:   "edit FILE EMPLOYEE",
+		Short: "Edit an employee",
+		Long: `Edit an employee given by username, ID or email address. Only the given flags are
+changed.`,
+		Args: cobra.ExactArgs(2),
+		Run: func(cmd *cobra.Command, args []string) {
+			book, err := gnucash.OpenBookFromSQLite(args[0],
+				gnucash.LoadTables(gnucash.TableSlots|gnucash.TableEmployees))
+			if err != nil {
+				fatal(err)
+			}
+			defer book.Close()
+			lockBook(book)
+
+			e := book.GetEmployeeByUsername(args[1])
+			if e == nil {
+				e = book.GetEmployeeByID(args[1])
+			}
+			if e == nil {
+				e = book.GetEmployeeByEmail(args[1])
+			}
+			if e == nil {
+				fatalf("Unable to find employee '%s'\n", args[1])
+			}
+
+			if employeeActive && employeeInactive {
+				fatal("Only one of --activate and --deactivate may be given")
+			}
+
+			if cmd.Flags().Changed("username") {
+				if o := book.GetEmployeeByUsername(employeeUsername); o != nil && o != e {
+					fatalf("Employee '%s' already exists\n", employeeUsername)
+				}
+				e.Username = employeeUsername
+			}
+			applyEmployeeFlags(cmd, book, e)
+
+			switch {
+			case employeeActive:
+				e.Active = 1
+			case employeeInactive:
+				e.Active = 0
+			}
+
+			if err := e.Write(); err != nil {
+				fatal(err)
+			}
+			printEmployee(e)
+		},
+	}
+)
+
+// Applies the employee flags given on the command line to e
+func applyEmployeeFlags(cmd *cobra.Command, book *gnucash.Book, e *gnucash.Employee) {
+	a := e.GetAddress()
+	if cmd.Flags().Changed("name") {
+		a.Name = employeeName
+	}
+	if cmd.Flags().Changed("email") {
+		if o := book.GetEmployeeByEmail(employeeEmail); employeeEmail != "" && o != nil && o != e {
+			fatalf("Email address '%s' already belongs to employee '%s'\n", employeeEmail, o.Username)
+		}
+		a.Email = employeeEmail
+	}
+	if cmd.Flags().Changed("phone") {
+		a.Phone = employeePhone
+	}
+	e.SetAddress(a)
+
+	if cmd.Flags().Changed("ccard-account") {
+		var acc *gnucash.Account
+		if employeeCCardAccount != "" {
+			acc = book.GetAccountByPath(employeeCCardAccount)
+			if acc == nil {
+				fatalf("Could not find account '%s'\n", employeeCCardAccount)
+			}
+		}
+		e.SetCCardAccount(acc)
+	}
+
+	if cmd.Flags().Changed("rate") {
+		r, ok := new(big.Rat).SetString(employeeRate)
+		if !ok {
+			fatalf("Invalid rate '%s'\n", employeeRate)
+		}
+		e.SetRate(r)
+	}
+}
+
+func printEmployee(e *gnucash.Employee) {
+	line := fmt.Sprintf("%s %s", e.Id, e.Username)
+	if e.AddrName.String != "" {
+		line += " (" + e.AddrName.String + ")"
+	}
+	if e.AddrEmail.String != "" {
+		line += " <" + e.AddrEmail.String + ">"
+	}
+	if acc := e.GetCCardAccount(); acc != nil {
+		line += ", card " + acc.GetPath()
+	}
+	if e.Active == 0 {
+		line += " [inactive]"
+	}
+
+	fmt.Println(line)
+}
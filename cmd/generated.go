@@ -0,0 +1,182 @@
+package cmd
+
+import (
+	"strings"
+
+	"bvorhofer.com/matchmaker/gnucash"
+)
+
+// Documents generated by monthly carry a 'gncx' KVP frame recording where
+// they came from and how to undo them. Documents monthly added entries to
+// (--append) keep their run, date and config, only the added entries and their
+// source splits are recorded with the run that added them:
+//
+//	gncx/run-id                 ID of the monthly run that generated the document
+//	gncx/run-date               date and time of that run
+//	gncx/config                 config file the bill was generated from
+//	gncx/period                 billing period the document was generated for
+//	gncx/added-entries/<GUID>   run that added entry <GUID> to an existing document
+//	gncx/splits/<GUID>          rule that matched the source split <GUID>
+//	gncx/split-runs/<GUID>      run that billed the source split <GUID>
+//	gncx/split-actions/<GUID>   action of payment split <GUID> before assignment
+//	gncx/split-lots/<GUID>      lot of payment split <GUID> before assignment
+//	gncx/split-accounts/<GUID>  account of payment split <GUID> before assignment
+//	gncx/txn-types/<GUID>       trans-txn-type of payment txn <GUID> before assignment
+//	gncx/overpayments/<GUID>    payment split the excess split <GUID> was split off from
+//	gncx/entries/<GUID>         bill the voucher entry <GUID> was split off from
+const (
+	generatedSlot           = "gncx"
+	generatedRunIdSlot      = generatedSlot + "/run-id"
+	generatedRunDateSlot    = generatedSlot + "/run-date"
+	generatedConfigSlot     = generatedSlot + "/config"
+	generatedPeriodSlot     = generatedSlot + "/period"
+	generatedAddedSlot      = generatedSlot + "/added-entries"
+	generatedSplitsSlot     = generatedSlot + "/splits"
+	generatedSplitRunsSlot  = generatedSlot + "/split-runs"
+	generatedActionsSlot    = generatedSlot + "/split-actions"
+	generatedLotsSlot       = generatedSlot + "/split-lots"
+	generatedAccountsSlot   = generatedSlot + "/split-accounts"
+	generatedTxnTypesSlot   = generatedSlot + "/txn-types"
+	generatedExcessSlot     = generatedSlot + "/overpayments"
+	generatedEntriesSlot    = generatedSlot + "/entries"
+	transactionTypeSlotName = "trans-txn-type"
+)
+
+// Provenance of a payment split that has already been billed
+type generatedRecord struct {
+	Invoice *gnucash.Invoice
+	Config  string
+	Rule    string
+}
+
+// Records the run that generated a document
+func recordRun(book *gnucash.Book, i *gnucash.Invoice, runId string, runDate string) {
+	setSlotString(book, i.Guid, generatedRunIdSlot, runId)
+	setSlotString(book, i.Guid, generatedRunDateSlot, runDate)
+}
+
+// Records the billing period a document was generated for
+func recordPeriod(book *gnucash.Book, i *gnucash.Invoice, period string) {
+	setSlotString(book, i.Guid, generatedPeriodSlot, period)
+}
+
+// Records that entry e was added to the existing document i by run runId
+func recordAddedEntry(book *gnucash.Book, i *gnucash.Invoice, e *gnucash.Entry, runId string) {
+	setSlotString(book, i.Guid, generatedAddedSlot+"/"+e.Guid, runId)
+}
+
+// Records the config file a document was generated from
+func recordConfig(book *gnucash.Book, i *gnucash.Invoice, config string) {
+	setSlotString(book, i.Guid, generatedConfigSlot, config)
+}
+
+// Records the source splits billed by run runId (with the rule that matched
+// them) in the KVP slots of a generated invoice
+func recordSources(book *gnucash.Book, i *gnucash.Invoice, runId string,
+	splits []*gnucash.Split, rules []string) {
+	for n, s := range splits {
+		setSlotString(book, i.Guid, generatedSplitsSlot+"/"+s.Guid, rules[n])
+		setSlotString(book, i.Guid, generatedSplitRunsSlot+"/"+s.Guid, runId)
+	}
+}
+
+// Records the bill a voucher entry has been split off from
+func recordVoucherEntry(book *gnucash.Book, voucher *gnucash.Invoice,
+	e *gnucash.Entry, billGuid string) {
+	setSlotString(book, voucher.Guid, generatedEntriesSlot+"/"+e.Guid, billGuid)
+}
+
+// Assigns split s as payment to invoice i posted to payable account ap, moving
+// s to ap if necessary. Remembers the original split actions, lots, accounts
+// and transaction type so the assignment can be undone. Returns the split
+// holding the credit with the vendor if s pays more than is due (s itself if
+// nothing was due), nil otherwise.
+func assignPayment(book *gnucash.Book, i *gnucash.Invoice, s *gnucash.Split,
+	ap *gnucash.Account) *gnucash.Split {
+	for _, ts := range s.Transaction.Splits {
+		if book.GetSlot(i.Guid, generatedActionsSlot+"/"+ts.Guid) != nil {
+			continue
+		}
+
+		setSlotString(book, i.Guid, generatedActionsSlot+"/"+ts.Guid, ts.Action)
+		setSlotString(book, i.Guid, generatedLotsSlot+"/"+ts.Guid, ts.LotGuid.String)
+		setSlotString(book, i.Guid, generatedAccountsSlot+"/"+ts.Guid, ts.AccountGuid)
+	}
+
+	if s.Account != ap {
+		if err := s.SetAccount(ap); err != nil {
+			fatal(err)
+		}
+	}
+
+	txnType := ""
+	if slot := book.GetSlot(s.Transaction.Guid, transactionTypeSlotName); slot != nil {
+		txnType = slot.StringVal.String
+	}
+	setSlotString(book, i.Guid, generatedTxnTypesSlot+"/"+s.Transaction.Guid, txnType)
+
+	credit, err := i.AssignPayment(s)
+	if err != nil {
+		fatal(err)
+	}
+	// Excess split off of s, undo merges it back
+	if credit != nil && credit != s {
+		setSlotString(book, i.Guid, generatedExcessSlot+"/"+credit.Guid, s.Guid)
+	}
+
+	return credit
+}
+
+// Sets a string slot (creating missing frames), exits on errors
+func setSlotString(book *gnucash.Book, objGuid string, path string, value string) {
+	if _, err := book.SetSlotString(objGuid, path, value); err != nil {
+		fatal(err)
+	}
+}
+
+// Returns the string value of a gncx slot, or "" if it doesn't exist
+func getGeneratedString(book *gnucash.Book, objGuid string, path string) string {
+	s := book.GetSlot(objGuid, path)
+	if s == nil {
+		return ""
+	}
+
+	return s.StringVal.String
+}
+
+// Returns the values of all slots in a gncx frame, key is the GUID the slot is
+// named after
+func getGeneratedFrame(book *gnucash.Book, objGuid string, path string) map[string]string {
+	vals := make(map[string]string)
+
+	frame := book.GetSlot(objGuid, path)
+	if frame == nil {
+		return vals
+	}
+
+	for _, s := range frame.GetChildren() {
+		vals[strings.TrimPrefix(s.Name, path+"/")] = s.StringVal.String
+	}
+
+	return vals
+}
+
+// Collects the provenance records of all generated invoices in the book, key
+// is the GUID of the source split
+func loadGeneratedRecords(book *gnucash.Book) map[string]generatedRecord {
+	records := make(map[string]generatedRecord)
+
+	for _, i := range book.GetInvoices() {
+		config := getGeneratedString(book, i.Guid, generatedConfigSlot)
+
+		for splitGuid, rule := range getGeneratedFrame(book, i.Guid, generatedSplitsSlot) {
+			records[splitGuid] = generatedRecord{
+				Invoice: i,
+				Config:  config,
+				Rule:    rule,
+			}
+		}
+	}
+
+	return records
+}
@@ -0,0 +1,68 @@
+package cmd
+
+import (
+	"errors"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"bvorhofer.com/matchmaker/gnucash"
+)
+
+// Book locked by the running command
+var lockedBook *gnucash.Book
+
+// Locks book like GnuCash does while the command writes to it. Refuses to if
+// GnuCash (or another command) has the book open, unless --force is given.
+// The lock is released by book.Close, by fatal and fatalf and when the
+// command is interrupted.
+func lockBook(book *gnucash.Book) {
+	if forceLock {
+		other, err := book.GetLock()
+		if err != nil {
+			log.Fatal(err)
+		}
+		if other != nil {
+			log.Printf("WARNING: Book is locked by %s, writing anyway\n", other)
+		}
+	}
+
+	if err := book.Lock(forceLock); err != nil {
+		if errors.Is(err, gnucash.ErrLocked) {
+			log.Fatalf("%v\nClose the book in GnuCash first or use --force to write anyway", err)
+		}
+		log.Fatal(err)
+	}
+	lockedBook = book
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM, syscall.SIGHUP)
+	go func() {
+		sig := <-signals
+		fatalf("Interrupted by %s, book may be incomplete\n", sig)
+	}()
+}
+
+// Releases the lock taken by lockBook, if any
+func unlockBook() {
+	if lockedBook == nil {
+		return
+	}
+
+	if err := lockedBook.Unlock(); err != nil {
+		log.Printf("WARNING: Unable to unlock book: %v\n", err)
+	}
+}
+
+// Like log.Fatal, but releases the lock of the book first
+func fatal(v ...interface{}) {
+	unlockBook()
+	log.Fatal(v...)
+}
+
+// Like log.Fatalf, but releases the lock of the book first
+func fatalf(format string, v ...interface{}) {
+	unlockBook()
+	log.Fatalf(format, v...)
+}
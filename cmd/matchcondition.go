@@ -0,0 +1,339 @@
+package cmd
+
+import (
+	"fmt"
+	"math/big"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"bvorhofer.com/matchmaker/gnucash"
+)
+
+// Condition a payment split has to fulfil for a monthly rule to match
+type splitCondition interface {
+	Matches(s *gnucash.Split) bool
+}
+
+// Matches if the split memo or the transaction description matches (either
+// regex may be nil)
+type textCondition struct {
+	memo *regexp.Regexp
+	desc *regexp.Regexp
+}
+
+func (c textCondition) Matches(s *gnucash.Split) bool {
+	if c.memo != nil && c.memo.MatchString(s.Memo) {
+		return true
+	}
+
+	if c.desc != nil && c.desc.MatchString(s.Transaction.Description.String) {
+		return true
+	}
+
+	return false
+}
+
+// Matches if the transaction number matches
+type numCondition struct {
+	num *regexp.Regexp
+}
+
+func (c numCondition) Matches(s *gnucash.Split) bool {
+	return c.num.MatchString(s.Transaction.Num)
+}
+
+// Matches if the absolute split value lies within [min, max], a nil bound is
+// unlimited. The sign of the split is ignored as it depends on the account
+// (payments are negative in bank accounts and positive in A/P), so bounds are
+// never negative and a refund matches like a payment of the same amount.
+type amountCondition struct {
+	min *big.Rat
+	max *big.Rat
+}
+
+func (c amountCondition) Matches(s *gnucash.Split) bool {
+	val := big.NewRat(s.ValueNum, s.ValueDenom)
+	val.Abs(val)
+
+	if c.min != nil && val.Cmp(c.min) < 0 {
+		return false
+	}
+
+	return c.max == nil || val.Cmp(c.max) <= 0
+}
+
+// Matches if another split of the same transaction belongs to account (or one
+// of its sub-accounts)
+type counterAccountCondition struct {
+	account *gnucash.Account
+}
+
+func (c counterAccountCondition) Matches(s *gnucash.Split) bool {
+	for _, o := range s.Transaction.Splits {
+		if o == s {
+			continue
+		}
+
+		for a := o.Account; a != nil; a = a.Parent {
+			if a == c.account {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// Matches if the transaction was posted on a day of month within [min, max]
+type dayCondition struct {
+	min int
+	max int
+}
+
+func (c dayCondition) Matches(s *gnucash.Split) bool {
+	d, err := time.Parse("2006-01-02 15:04:05", s.Transaction.PostDate.String)
+	if err != nil {
+		return false
+	}
+
+	return d.Day() >= c.min && d.Day() <= c.max
+}
+
+// Matches if all conditions match
+type allCondition []splitCondition
+
+func (c allCondition) Matches(s *gnucash.Split) bool {
+	for _, cc := range c {
+		if !cc.Matches(s) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Matches if any condition matches
+type anyCondition []splitCondition
+
+func (c anyCondition) Matches(s *gnucash.Split) bool {
+	for _, cc := range c {
+		if cc.Matches(s) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// YAML representation of a match condition. All given fields have to match,
+// except for memo and description of which either one suffices. Nested any/all
+// lists allow arbitrary combinations, e.g.
+//
+//	match:
+//	  counter-account: Assets:Bank
+//	  any:
+//	    - description: Strom
+//	    - amount: 50.00
+//	      tolerance: 10%
+//	      day: 1-5
+//
+// Amounts are compared to the absolute value of the split, i.e. ignoring
+// whether it is a payment or a refund, and must not be negative.
+type yamlCondition struct {
+	Memo           string
+	Description    string
+	Num            string
+	Amount         string
+	AmountMin      string `yaml:"amount-min"`
+	AmountMax      string `yaml:"amount-max"`
+	Tolerance      string
+	CounterAccount string `yaml:"counter-account"`
+	Day            string
+	All            []yamlCondition
+	Any            []yamlCondition
+}
+
+// Builds a condition from its YAML representation, reporting all problems
+// found via fail
+func parseCondition(book *gnucash.Book, yc yamlCondition,
+	fail func(format string, a ...interface{})) splitCondition {
+	conds := allCondition{}
+
+	if yc.Memo != "" || yc.Description != "" {
+		var tc textCondition
+		var err error
+		if yc.Memo != "" {
+			tc.memo, err = regexp.Compile(yc.Memo)
+			if err != nil {
+				fail("invalid memo regex: %s", err)
+			}
+		}
+
+		if yc.Description != "" {
+			tc.desc, err = regexp.Compile(yc.Description)
+			if err != nil {
+				fail("invalid description regex: %s", err)
+			}
+		}
+
+		conds = append(conds, tc)
+	}
+
+	if yc.Num != "" {
+		num, err := regexp.Compile(yc.Num)
+		if err != nil {
+			fail("invalid num regex: %s", err)
+		}
+
+		conds = append(conds, numCondition{num})
+	}
+
+	if c := parseAmountCondition(yc, fail); c != nil {
+		conds = append(conds, c)
+	}
+
+	if yc.CounterAccount != "" {
+		acc := book.GetAccountByPath(yc.CounterAccount)
+		if acc == nil {
+			fail("unable to find counter account '%s'", yc.CounterAccount)
+		}
+
+		conds = append(conds, counterAccountCondition{acc})
+	}
+
+	if yc.Day != "" {
+		c, err := parseDayCondition(yc.Day)
+		if err != nil {
+			fail("%s", err)
+		}
+
+		conds = append(conds, c)
+	}
+
+	if len(yc.All) > 0 {
+		allOf := allCondition{}
+		for _, sub := range yc.All {
+			allOf = append(allOf, parseCondition(book, sub, fail))
+		}
+
+		conds = append(conds, allOf)
+	}
+
+	if len(yc.Any) > 0 {
+		anyOf := anyCondition{}
+		for _, sub := range yc.Any {
+			anyOf = append(anyOf, parseCondition(book, sub, fail))
+		}
+
+		conds = append(conds, anyOf)
+	}
+
+	if len(conds) == 0 {
+		fail("empty match condition")
+	}
+
+	if len(conds) == 1 {
+		return conds[0]
+	}
+
+	return conds
+}
+
+// Parses amount, amount-min, amount-max and tolerance fields. Tolerance is
+// either absolute or a percentage of the amount. Amounts can't be negative as
+// matching ignores the sign of the split.
+func parseAmountCondition(yc yamlCondition, fail func(format string, a ...interface{})) splitCondition {
+	if yc.Amount == "" && yc.AmountMin == "" && yc.AmountMax == "" {
+		if yc.Tolerance != "" {
+			fail("tolerance given without amount")
+		}
+
+		return nil
+	}
+
+	parse := func(name string, val string) *big.Rat {
+		r, ok := new(big.Rat).SetString(val)
+		if !ok {
+			fail("invalid %s '%s'", name, val)
+			return new(big.Rat)
+		}
+
+		return r
+	}
+
+	parseAmount := func(name string, val string) *big.Rat {
+		r := parse(name, val)
+		if r.Sign() < 0 {
+			fail("%s '%s' is negative, amounts are matched regardless of their sign", name, val)
+			r.Abs(r)
+		}
+
+		return r
+	}
+
+	if yc.Amount != "" {
+		if yc.AmountMin != "" || yc.AmountMax != "" {
+			fail("amount can't be combined with amount-min or amount-max")
+		}
+
+		amount := parseAmount("amount", yc.Amount)
+		tolerance := new(big.Rat)
+		if strings.HasSuffix(yc.Tolerance, "%") {
+			tolerance = parse("tolerance", strings.TrimSuffix(yc.Tolerance, "%"))
+			tolerance.Mul(tolerance, amount)
+			tolerance.Mul(tolerance, big.NewRat(1, 100))
+		} else if yc.Tolerance != "" {
+			tolerance = parse("tolerance", yc.Tolerance)
+		}
+		tolerance.Abs(tolerance)
+
+		min := new(big.Rat).Sub(amount, tolerance)
+		if min.Sign() < 0 {
+			min.SetInt64(0)
+		}
+
+		return amountCondition{
+			min: min,
+			max: new(big.Rat).Add(amount, tolerance),
+		}
+	}
+
+	if yc.Tolerance != "" {
+		fail("tolerance can only be combined with amount")
+	}
+
+	c := amountCondition{}
+	if yc.AmountMin != "" {
+		c.min = parseAmount("amount-min", yc.AmountMin)
+	}
+	if yc.AmountMax != "" {
+		c.max = parseAmount("amount-max", yc.AmountMax)
+	}
+	if c.min != nil && c.max != nil && c.min.Cmp(c.max) > 0 {
+		fail("amount-min %s is greater than amount-max %s", yc.AmountMin, yc.AmountMax)
+	}
+
+	return c
+}
+
+// Parses a day of month ('5') or a range of days ('1-5')
+func parseDayCondition(day string) (dayCondition, error) {
+	bounds := strings.SplitN(day, "-", 2)
+
+	min, err := strconv.Atoi(strings.TrimSpace(bounds[0]))
+	if err != nil || min < 1 || min > 31 {
+		return dayCondition{}, fmt.Errorf("invalid day '%s'", day)
+	}
+
+	max := min
+	if len(bounds) == 2 {
+		max, err = strconv.Atoi(strings.TrimSpace(bounds[1]))
+		if err != nil || max < min || max > 31 {
+			return dayCondition{}, fmt.Errorf("invalid day range '%s'", day)
+		}
+	}
+
+	return dayCondition{min, max}, nil
+}
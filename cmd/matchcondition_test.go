@@ -0,0 +1,156 @@
+package cmd
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+	"testing"
+
+	"bvorhofer.com/matchmaker/gnucash"
+)
+
+func TestParseAmountCondition(t *testing.T) {
+	tests := []struct {
+		name string
+		yc   yamlCondition
+		min  string // "" for unlimited
+		max  string
+		errs []string
+	}{
+		{"none", yamlCondition{}, "", "", nil},
+		{"exact", yamlCondition{Amount: "50"}, "50", "50", nil},
+		{"decimal", yamlCondition{Amount: "12.99"}, "1299/100", "1299/100", nil},
+		{"absolute tolerance", yamlCondition{Amount: "50", Tolerance: "2.5"}, "95/2", "105/2", nil},
+		{"percent tolerance", yamlCondition{Amount: "50", Tolerance: "10%"}, "45", "55", nil},
+		{"fractional percent tolerance", yamlCondition{Amount: "80", Tolerance: "2.5%"}, "78", "82", nil},
+		{"negative tolerance", yamlCondition{Amount: "50", Tolerance: "-10%"}, "45", "55", nil},
+		{"tolerance above amount", yamlCondition{Amount: "5", Tolerance: "10"}, "0", "15", nil},
+		{"range", yamlCondition{AmountMin: "40", AmountMax: "60"}, "40", "60", nil},
+		{"equal bounds", yamlCondition{AmountMin: "40", AmountMax: "40"}, "40", "40", nil},
+		{"min only", yamlCondition{AmountMin: "40"}, "40", "", nil},
+		{"max only", yamlCondition{AmountMax: "60"}, "", "60", nil},
+		{"invalid amount", yamlCondition{Amount: "fifty"}, "0", "0",
+			[]string{"invalid amount 'fifty'"}},
+		{"invalid tolerance", yamlCondition{Amount: "50", Tolerance: "ten%"}, "50", "50",
+			[]string{"invalid tolerance 'ten'"}},
+		{"negative amount", yamlCondition{Amount: "-50"}, "50", "50",
+			[]string{"amount '-50' is negative"}},
+		{"negative bounds", yamlCondition{AmountMin: "-60", AmountMax: "-40"}, "60", "40",
+			[]string{"amount-min '-60' is negative", "amount-max '-40' is negative",
+				"amount-min -60 is greater than amount-max -40"}},
+		{"min above max", yamlCondition{AmountMin: "60", AmountMax: "40"}, "60", "40",
+			[]string{"amount-min 60 is greater than amount-max 40"}},
+		{"amount and range", yamlCondition{Amount: "50", AmountMin: "40"}, "50", "50",
+			[]string{"amount can't be combined with amount-min or amount-max"}},
+		{"tolerance without amount", yamlCondition{Tolerance: "10%"}, "", "",
+			[]string{"tolerance given without amount"}},
+		{"tolerance with range", yamlCondition{AmountMin: "40", Tolerance: "10%"}, "40", "",
+			[]string{"tolerance can only be combined with amount"}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			errs := []error{}
+			fail := func(format string, a ...interface{}) {
+				errs = append(errs, fmt.Errorf(format, a...))
+			}
+
+			c := parseAmountCondition(tc.yc, fail)
+			checkErrors(t, errs, tc.errs...)
+
+			if tc.yc.Amount == "" && tc.yc.AmountMin == "" && tc.yc.AmountMax == "" {
+				if c != nil {
+					t.Errorf("condition %+v, want none", c)
+				}
+				return
+			}
+
+			ac, ok := c.(amountCondition)
+			if !ok {
+				t.Fatalf("condition %+v, want an amount condition", c)
+			}
+
+			bound := func(r *big.Rat) string {
+				if r == nil {
+					return ""
+				}
+				return r.RatString()
+			}
+			if got := bound(ac.min); got != tc.min {
+				t.Errorf("min %q, want %q", got, tc.min)
+			}
+			if got := bound(ac.max); got != tc.max {
+				t.Errorf("max %q, want %q", got, tc.max)
+			}
+		})
+	}
+}
+
+func TestAmountConditionMatches(t *testing.T) {
+	c := parseAmountCondition(yamlCondition{Amount: "50", Tolerance: "10%"},
+		func(format string, a ...interface{}) { t.Errorf(format, a...) })
+
+	tests := []struct {
+		value int64 // in cents
+		want  bool
+	}{
+		{5000, true},
+		{4500, true},
+		{5500, true},
+		{4499, false},
+		{5501, false},
+		// Payments are negative in bank accounts, refunds positive
+		{-5000, true},
+		{-5501, false},
+	}
+
+	for _, tc := range tests {
+		s := &gnucash.Split{DbSplit: gnucash.DbSplit{ValueNum: tc.value, ValueDenom: 100}}
+		if got := c.Matches(s); got != tc.want {
+			t.Errorf("%d/100: match %v, want %v", tc.value, got, tc.want)
+		}
+	}
+}
+
+func TestParseDayCondition(t *testing.T) {
+	tests := []struct {
+		day string
+		min int
+		max int
+		err string
+	}{
+		{"5", 5, 5, ""},
+		{"1-5", 1, 5, ""},
+		{" 1 - 5 ", 1, 5, ""},
+		{"28-31", 28, 31, ""},
+		{"15-15", 15, 15, ""},
+		{"0", 0, 0, "invalid day '0'"},
+		{"32", 0, 0, "invalid day '32'"},
+		{"first", 0, 0, "invalid day 'first'"},
+		{"", 0, 0, "invalid day ''"},
+		{"-5", 0, 0, "invalid day '-5'"},
+		{"5-1", 0, 0, "invalid day range '5-1'"},
+		{"1-32", 0, 0, "invalid day range '1-32'"},
+		{"1-", 0, 0, "invalid day range '1-'"},
+		{"1-5-7", 0, 0, "invalid day range '1-5-7'"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.day, func(t *testing.T) {
+			c, err := parseDayCondition(tc.day)
+			if tc.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.err) {
+					t.Errorf("error %v, want %q", err, tc.err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+			if c.min != tc.min || c.max != tc.max {
+				t.Errorf("days %d-%d, want %d-%d", c.min, c.max, tc.min, tc.max)
+			}
+		})
+	}
+}
@@ -1,16 +1,14 @@
 package cmd
 
 import (
+	"bufio"
 	"database/sql"
-	"encoding/csv"
 	"fmt"
 	"io/ioutil"
 	"log"
 	"math/big"
 	"os"
-	"path/filepath"
-	"regexp"
-	"strconv"
+	"sort"
 	"strings"
 	"time"
 
@@ -22,25 +20,37 @@ var (
 	monthlyCmd = &cobra.Command{
 		Use:   "monthly FILE",
 		Short: "Generate bills and vouchers from configs in current directory",
-		Long:  `Generate bills and vouchers from configs in current directory`,
-		Args:  cobra.ExactArgs(1),
+		Long: `Generate bills and vouchers from configs in current directory. Configs are YAML files
+(*.yaml, *.yml) naming the vendor explicitly, or headerless CSV files (*.csv) named after the
+vendor. Vendors are found by name or alias; missing ones can be created with --create-vendors.`,
+		Args: cobra.ExactArgs(1),
 		Run: func(cmd *cobra.Command, args []string) {
 			// Open GnuCash file
 			book, err := gnucash.OpenBookFromSQLite(args[0])
 			if err != nil {
-				log.Fatal(err)
+				fatal(err)
 			}
 			defer book.Close()
+			lockBook(book)
 
+			// Identify this run so its documents can be found (and undone) later
+			runDate := gnucash.GetCurrentTimeString()
+			runId := time.Now().Format("20060102150405")
+			fmt.Printf("RUN %s\n", runId)
+
+			billFormat, err := book.GetBillCounterFormat()
+			if err != nil {
+				fatal(err)
+			}
 			fmt.Println("Book bill counter format is:")
-			fmt.Println(book.GetBillCounterFormat())
+			fmt.Println(billFormat)
 
 			// Process date args (if given)
 			var startDateTime time.Time
 			if startDate != "" {
 				startDateTime, err = time.Parse("2006-01-02", startDate)
 				if err != nil {
-					log.Fatal("Invalid start date given, use format YYYY-MM-DD")
+					fatal("Invalid start date given, use format YYYY-MM-DD")
 				}
 
 				log.Println("Start date is", startDateTime.String())
@@ -50,7 +60,7 @@ var (
 			if endDate != "" {
 				endDateTime, err = time.Parse("2006-01-02", endDate)
 				if err != nil {
-					log.Fatal("Invalid end date given, use format YYYY-MM-DD")
+					fatal("Invalid end date given, use format YYYY-MM-DD")
 				}
 
 				// Add one day to end date so it's exclusive
@@ -60,52 +70,141 @@ var (
 				log.Println("End date (exclusive) is", endDateTime.String())
 			}
 
+			switch dateStrategy {
+			case "earliest", "latest", "period-end", "today":
+			default:
+				fatalf("Invalid date strategy '%s'", dateStrategy)
+			}
+
+			switch groupBy {
+			case "none", "month", "week", "transaction":
+			default:
+				fatalf("Invalid grouping '%s'", groupBy)
+			}
+
 			items, err := ioutil.ReadDir(".")
 			if err != nil {
-				log.Fatal("Error reading current directory: " + err.Error())
+				fatal("Error reading current directory: " + err.Error())
 			}
 
 			// Find payable account
-			ap := book.GetAccountByPath(payableAccount)
-			if ap == nil {
-				log.Fatalf("Could not find account '%s'\n", payableAccount)
+			defaultAp := book.GetAccountByPath(payableAccount)
+			if defaultAp == nil {
+				fatalf("Could not find account '%s'\n", payableAccount)
 			}
 
-			// Employee expense voucher entries, key is employee GUID
-			voucherItems := make(map[string][]*gnucash.Entry)
+			// Find employee liability account vouchers are posted to
+			employeeAcc := defaultAp
+			if employeeAccount != "" {
+				employeeAcc = book.GetAccountByPath(employeeAccount)
+				if employeeAcc == nil {
+					fatalf("Could not find account '%s'\n", employeeAccount)
+				}
+			}
+
+			currency, err := book.GetDefaultCurrency()
+			if err != nil {
+				fatal(err)
+			}
 
+			// Load and validate all config files before generating anything
+			configs := []*monthlyConfig{}
+			configErrors := []error{}
 			for _, item := range items {
-				if !strings.HasSuffix(item.Name(), ".csv") {
+				if item.IsDir() || !isMonthlyConfigFile(item.Name()) {
 					continue
 				}
 
-				// Open CSV config file
-				f, err := os.Open(item.Name())
-				if err != nil {
-					log.Fatal("Error opening match file: " + err.Error())
+				c, errs := loadMonthlyConfig(book, item.Name())
+				configErrors = append(configErrors, errs...)
+				if c != nil {
+					configs = append(configs, c)
 				}
+			}
 
-				r := csv.NewReader(f)
-				matches, err := r.ReadAll()
-				if err != nil {
-					log.Fatal("Error reading match file: " + err.Error())
+			if len(configErrors) > 0 {
+				for _, err := range configErrors {
+					log.Println(err)
+				}
+				fatalf("%d error(s) in config files\n", len(configErrors))
+			}
+
+			createMissingVendors(book, configs)
+
+			// Payments billed by previous runs, key is split GUID
+			generated := loadGeneratedRecords(book)
+
+			// Employee expense voucher entries, key is billing period and
+			// employee GUID
+			voucherGroups := make(map[string]*voucherGroup)
+
+			// GUIDs of the bills that voucher entries were split off from
+			voucherSources := make(map[*gnucash.Entry]string)
+
+			for _, config := range configs {
+				vendor := config.Vendor
+				customer := config.Customer
+
+				ap := defaultAp
+				if config.PayableAccount != nil {
+					ap = config.PayableAccount
+				}
+
+				// Customer configs generate invoices posted to the receivable account
+				postAcc := ap
+				ownerType, ownerGuid := gnucash.OwnerTypeVendor, ""
+				docLabel, ownerLabel := "", ""
+				if customer != nil {
+					postAcc = config.ReceivableAccount
+					if postAcc == nil {
+						postAcc = book.GetAccountByPath(receivableAccount)
+						if postAcc == nil {
+							fatalf("Could not find account '%s'\n", receivableAccount)
+						}
+					}
+
+					ownerType, ownerGuid = gnucash.OwnerTypeCustomer, customer.Guid
+					docLabel, ownerLabel = "INVOICE", "for customer "+customer.Name
+					fmt.Printf("CONFIG %s for customer %s\n", config.File, customer.Name)
+				} else {
+					ownerGuid = vendor.Guid
+					docLabel, ownerLabel = "BILL", "from vendor "+vendor.Name
+					fmt.Printf("CONFIG %s for vendor %s\n", config.File, vendor.Name)
 				}
 
-				// Find vendor based on filename
-				vendorName := strings.TrimSuffix(filepath.Base(f.Name()), filepath.Ext(f.Name()))
-				vendor := book.GetVendorByName(vendorName)
-				if vendor == nil {
-					log.Fatalf("Unable to find vendor '%s'", vendorName)
+				// Documents of a job are owned by the job instead
+				if config.Job != nil {
+					ownerType, ownerGuid = gnucash.OwnerTypeJob, config.Job.Guid
+					ownerLabel += " (job " + config.Job.Name + ")"
 				}
 
-				fmt.Printf("CONFIG %s for vendor %s\n", item.Name(), vendorName)
+				// Matches grouped by billing period, key is period key
+				billGroups := make(map[string]*billGroup)
+				group := func(period string, periodEnd time.Time) *billGroup {
+					g := billGroups[period]
+					if g == nil {
+						g = &billGroup{periodEnd: periodEnd}
+						billGroups[period] = g
+					}
+
+					return g
+				}
+				skipped := 0
+
+				// Search splits in source accounts (by default the payable
+				// account) for matches
+				sources := config.SourceAccounts
+				if len(sources) == 0 {
+					sources = []*gnucash.Account{ap}
+				}
 
-				entries := []*gnucash.Entry{}
-				matchSplits := []*gnucash.Split{}
+				sourceSplits := []*gnucash.Split{}
+				for _, acc := range sources {
+					sourceSplits = append(sourceSplits, acc.Splits...)
+				}
 
-				// Search splits in payable account for matches
-				// CSV format: SPLIT MEMO REGEX, TXN DESC REGEX, ENTRY DESCR, DEST ACCT
-				for _, s := range ap.Splits {
+				seenTxns := make(map[*gnucash.Transaction]bool)
+				for _, s := range sourceSplits {
 					if !s.Transaction.PostDate.Valid {
 						log.Printf("WARNING: Transaction %s has no post date, skipping...\n", s.Transaction.Guid)
 						continue
@@ -130,41 +229,57 @@ var (
 						continue
 					}
 
-					// Ignore splits which have been assigned as payment already
-					if s.LotGuid.Valid && s.LotGuid.String != "" {
+					// Ignore splits which have been billed by a previous run
+					if rec, ok := generated[s.Guid]; ok {
+						if rec.Config == config.File {
+							amt := big.NewRat(s.ValueNum, s.ValueDenom)
+							fmt.Printf(" = %s %50s %s already billed in %s (rule %s)\n",
+								s.Transaction.PostDate.String, s.Transaction.Description.String,
+								amt.String(), rec.Invoice.Id, rec.Rule)
+							skipped++
+						}
+						continue
+					}
+
+					// Ignore transactions seen via another source account
+					if seenTxns[s.Transaction] {
 						continue
 					}
+					seenTxns[s.Transaction] = true
 
-					// Ignore splits which come from bills or were assigned payments
-					if s.Action == "Payment" || s.Action == "Bill" {
+					// Ignore splits which come from posted documents or were assigned
+					// payments, except for costs from bills re-billed to customers
+					if isBusinessAction(s.Action) && (customer == nil || s.Action != "Bill") {
 						continue
 					}
 
-					for i, m := range matches {
-						memoMatch := false
-						if m[0] != "" {
-							memoMatch, err = regexp.MatchString(m[0], s.Memo)
-							if err != nil {
-								log.Fatalf("Error in regex %s on line %d in bill generator config",
-									m[0], i)
+					for _, rule := range config.Rules {
+						if rule.Matches(s) {
+							// Re-bill (a share of) the matched split to the customer
+							if customer != nil {
+								g := group(billingPeriod(s, tDate, endDateTime))
+								g.entries = append(g.entries, invoiceEntry(s, rule))
+								g.splits = append(g.splits, s)
+								g.rules = append(g.rules, rule.Name)
+								g.dates = append(g.dates, tDate)
+								break
 							}
-						}
 
-						descMatch := false
-						if m[1] != "" {
-							descMatch, err = regexp.MatchString(m[1],
-								s.Transaction.Description.String)
-							if err != nil {
-								log.Fatalf("Error in regex %s on line %d in bill generator config",
-									m[1], i)
+							// Find the split that will be assigned to the bill
+							ps := paymentSplit(s, ap)
+							if ps == nil {
+								log.Printf("WARNING: Transaction %s matches rule %s but has no split that"+
+									" can be moved to %s, skipping...\n", s.Transaction.Guid, rule.Name, ap.Name.String)
+								break
+							}
+
+							// Ignore splits which have been assigned as payment already
+							if ps.LotGuid.Valid && ps.LotGuid.String != "" {
+								break
 							}
-						}
 
-						if memoMatch || descMatch {
-							// Find destination account
-							bAcc := book.GetAccountByPath(m[3])
-							if bAcc == nil {
-								log.Fatalf("Unable to find account '%s'", m[3])
+							if isBusinessAction(ps.Action) {
+								break
 							}
 
 							// Create entry for this split
@@ -176,49 +291,52 @@ var (
 								DbEntry: gnucash.DbEntry{
 									Date:          date,
 									DateEntered:   sql.NullString{gnucash.GetCurrentTimeString(), true},
-									Description:   sql.NullString{m[2], true},
+									Description:   sql.NullString{rule.Description, true},
 									QuantityNum:   sql.NullInt64{1, true},
 									QuantityDenom: sql.NullInt64{1, true},
-									BAcct:         sql.NullString{bAcc.Guid, true},
-									BPriceNum:     sql.NullInt64{s.ValueNum, true},
-									BPriceDenom:   sql.NullInt64{s.ValueDenom, true},
+									BAcct:         sql.NullString{rule.Account.Guid, true},
+									BPriceNum:     sql.NullInt64{ps.ValueNum, true},
+									BPriceDenom:   sql.NullInt64{ps.ValueDenom, true},
 								},
 							}
-							entries = append(entries, entry)
-							matchSplits = append(matchSplits, s)
-
-							// Find employees for reimbursements (optional)
-							for j := 4; j < len(m)-1; j += 2 {
-								if m[j] == "" {
-									break
-								}
-
-								e := book.GetEmployeeByUsername(m[j])
-								if e == nil {
-									log.Fatalf("Unable to find employee '%s'", m[j])
-								}
+							// Payments are gross amounts, so taxes are included in the price
+							if rule.TaxTable != nil {
+								entry.SetBillTaxTable(rule.TaxTable, true)
+							}
+							period, periodEnd := billingPeriod(s, tDate, endDateTime)
+							g := group(period, periodEnd)
 
-								ss := strings.Split(m[j+1], "/")
-								if len(ss) != 2 {
-									log.Fatalf("Invalid rational '%s'", m[j+1])
-								}
-								num, err := strconv.ParseInt(ss[0], 10, 64)
-								if err != nil {
-									log.Fatalf("Invalid numerator in '%s'", m[j+1])
-								}
+							g.entries = append(g.entries, entry)
+							g.splits = append(g.splits, s)
+							g.payments = append(g.payments, ps)
+							g.rules = append(g.rules, rule.Name)
+							g.dates = append(g.dates, tDate)
 
-								denom, err := strconv.ParseInt(ss[1], 10, 64)
-								if err != nil {
-									log.Fatalf("Invalid denominator in '%s'", m[j+1])
-								}
+							// Split entry among employees for reimbursement (optional)
+							for _, share := range rule.Shares {
+								e := share.Employee
 
 								// Re-use (copy) bill entry for voucher, but update quantity to specified share
 								// NOTE: quantity sign is reversed for credit notes (not sure why)
 								ent := *entry
-								ent.QuantityNum = sql.NullInt64{-num, true}
-								ent.QuantityDenom = sql.NullInt64{denom, true}
+								ent.QuantityNum = sql.NullInt64{-share.Share.Num().Int64(), true}
+								ent.QuantityDenom = sql.NullInt64{share.Share.Denom().Int64(), true}
 
-								voucherItems[e.Guid] = append(voucherItems[e.Guid], &ent)
+								// Expenses paid with the employee's credit card are booked against the card
+								ent.BPaytype = sql.NullInt32{int32(gnucash.EntryPaymentTypeCash), true}
+								if paidByCard(s, e) {
+									ent.BPaytype = sql.NullInt32{int32(gnucash.EntryPaymentTypeCard), true}
+								}
+
+								vg := voucherGroups[period+" "+e.Guid]
+								if vg == nil {
+									vg = &voucherGroup{employeeGuid: e.Guid, period: period, periodEnd: periodEnd}
+									voucherGroups[period+" "+e.Guid] = vg
+								}
+
+								vg.entries = append(vg.entries, &ent)
+								vg.dates = append(vg.dates, tDate)
+								g.voucherItems = append(g.voucherItems, &ent)
 							}
 
 							break
@@ -226,73 +344,526 @@ var (
 					}
 				}
 
-				// If any entries were created, create a new bill and add the
-				// entries
-				if len(entries) > 0 {
-					invoice := &gnucash.Invoice{
-						IsCreditNote: false,
-						DbInvoice: gnucash.DbInvoice{
-							Id:             fmt.Sprintf(book.GetBillCounterFormat(), book.GetBillCounter()),
-							DateOpened:     sql.NullString{gnucash.GetCurrentTimeString(), true},
-							Notes:          fmt.Sprintf("Generated by gncx (%s)", f.Name()),
-							Active:         1,
-							Currency:       book.GetDefaultCurrency().Guid,
-							OwnerType:      sql.NullInt32{int32(gnucash.OwnerTypeVendor), true},
-							OwnerGuid:      sql.NullString{vendor.Guid, true},
-							ChargeAmtNum:   sql.NullInt64{0, true},
-							ChargeAmtDenom: sql.NullInt64{1, true},
-						},
+				if len(billGroups) == 0 && skipped > 0 {
+					fmt.Printf("NOTHING TO DO, %d payment(s) already billed\n", skipped)
+				}
+
+				// Create one bill (or invoice) per billing period (in chronological order)
+				periods := []string{}
+				for period := range billGroups {
+					periods = append(periods, period)
+				}
+				sort.Strings(periods)
+
+				for _, period := range periods {
+					g := billGroups[period]
+					billDate := documentDate(g.dates, g.periodEnd)
+
+					// Add to an open document of the same period if requested
+					var invoice *gnucash.Invoice
+					if appendDocuments {
+						invoice = findOpenDocument(book, ownerGuid, false, period, endDateTime)
+					}
+					appended := invoice != nil
+
+					if appended {
+						fmt.Printf("%s %s %s (adding to open document)\n", docLabel, invoice.Id, ownerLabel)
+					} else {
+						id := documentId(book.GetNextBillId)
+						if customer != nil {
+							id = documentId(book.GetNextInvoiceId)
+						}
+
+						invoice = &gnucash.Invoice{
+							IsCreditNote: false,
+							DbInvoice: gnucash.DbInvoice{
+								Id:             id,
+								DateOpened:     sql.NullString{billDate, true},
+								Notes:          fmt.Sprintf("Generated by gncx (%s)", config.File),
+								Active:         1,
+								Currency:       currency.Guid,
+								OwnerType:      sql.NullInt32{int32(ownerType), true},
+								OwnerGuid:      sql.NullString{ownerGuid, true},
+								ChargeAmtNum:   sql.NullInt64{0, true},
+								ChargeAmtDenom: sql.NullInt64{1, true},
+							},
+						}
+						if config.Terms != nil {
+							invoice.SetTerms(config.Terms)
+						}
+						if err := book.AddInvoice(invoice); err != nil {
+							fatal(err)
+						}
+						recordRun(book, invoice, runId, runDate)
+						recordConfig(book, invoice, config.File)
+						recordPeriod(book, invoice, period)
+
+						fmt.Printf("%s %s %s\n", docLabel, invoice.Id, ownerLabel)
 					}
-					book.AddInvoice(invoice)
 
-					fmt.Printf("BILL %s from vendor %s\n", invoice.Id, invoice.GetVendor().Name)
+					for _, e := range g.entries {
+						if err := invoice.AddEntry(e); err != nil {
+							fatal(err)
+						}
+						gross, err := e.GetGross()
+						if err != nil {
+							fatal(err)
+						}
+						fmt.Printf(" * %s %50s %s\n", e.Date, e.Description.String, gross.String())
+						if appended {
+							recordAddedEntry(book, invoice, e, runId)
+						}
+					}
 
-					for _, e := range entries {
-						amt := big.NewRat(e.BPriceNum.Int64, e.BPriceDenom.Int64)
-						fmt.Printf(" * %s %50s %s\n", e.Date, e.Description.String, amt.String())
-						invoice.AddEntry(e)
+					// Post the bill to A/P account (or the invoice to A/R account)
+					if err := invoice.Post(postAcc, billDate, ""); err != nil {
+						fatal(err)
+					}
+					if t := invoice.GetTerms(); t != nil {
+						fmt.Printf("   due %s (%s)\n", strings.SplitN(invoice.GetDateDue(), " ", 2)[0], t.Name)
 					}
 
-					// Post the invoice to A/P account
-					invoice.Post(ap)
+					// Remember where the document came from so later runs skip its splits
+					recordSources(book, invoice, runId, g.splits, g.rules)
 
-					for _, s := range matchSplits {
+					for _, s := range g.payments {
 						amt := big.NewRat(s.ValueNum, s.ValueDenom)
 						fmt.Printf(" P %s %50s %s\n", s.Transaction.PostDate.String, s.Transaction.Description.String, amt.String())
-						invoice.AssignPayment(s)
+						if credit := assignPayment(book, invoice, s, ap); credit == s {
+							fmt.Printf("   nothing due, credit balance with vendor\n")
+						} else if credit != nil {
+							amt := big.NewRat(credit.ValueNum, credit.ValueDenom)
+							fmt.Printf("   overpaid by %s, credit balance with vendor\n", amt.FloatString(2))
+						}
+					}
+
+					if vendor != nil && !invoice.IsPaid() {
+						due, err := invoice.GetAmountDue()
+						if err != nil {
+							fatal(err)
+						}
+						log.Printf("WARNING: Bill %s remains open, %s still due\n",
+							invoice.Id, due.FloatString(2))
+					}
+
+					for _, e := range g.voucherItems {
+						voucherSources[e] = invoice.Guid
 					}
 				}
 			}
 
-			// Generate expense vouchers
-			for empGuid, entries := range voucherItems {
-				voucher := &gnucash.Invoice{
-					IsCreditNote: true,
-					DbInvoice: gnucash.DbInvoice{
-						Id:             fmt.Sprintf(book.GetExpenseVoucherCounterFormat(), book.GetExpenseVoucherCounter()),
-						DateOpened:     sql.NullString{gnucash.GetCurrentTimeString(), true},
-						Notes:          "Generated by gncx",
-						Active:         1,
-						Currency:       book.GetDefaultCurrency().Guid,
-						OwnerType:      sql.NullInt32{int32(gnucash.OwnerTypeEmployee), true},
-						OwnerGuid:      sql.NullString{empGuid, true},
-						ChargeAmtNum:   sql.NullInt64{0, true},
-						ChargeAmtDenom: sql.NullInt64{1, true},
-					},
+			// Generate expense vouchers (one per employee and billing period)
+			voucherKeys := []string{}
+			for key := range voucherGroups {
+				voucherKeys = append(voucherKeys, key)
+			}
+			sort.Strings(voucherKeys)
+
+			// Amount charged per employee (username) and bill (ID)
+			charged := make(map[string]map[string]*big.Rat)
+
+			for _, key := range voucherKeys {
+				vg := voucherGroups[key]
+
+				// Add to an open voucher of the same period if requested
+				var voucher *gnucash.Invoice
+				if appendDocuments {
+					voucher = findOpenDocument(book, vg.employeeGuid, true, vg.period, endDateTime)
 				}
-				book.AddInvoice(voucher)
 
-				fmt.Printf("VOUCHER %s for employee %s\n", voucher.Id, voucher.GetEmployee().Username)
+				appended := voucher != nil
 
-				for _, entry := range entries {
+				if appended {
+					fmt.Printf("VOUCHER %s for employee %s (adding to open voucher)\n", voucher.Id,
+						voucher.GetEmployee().Username)
+				} else {
+					voucher = &gnucash.Invoice{
+						IsCreditNote: true,
+						DbInvoice: gnucash.DbInvoice{
+							Id:             documentId(book.GetNextExpenseVoucherId),
+							DateOpened:     sql.NullString{documentDate(vg.dates, vg.periodEnd), true},
+							Notes:          "Generated by gncx",
+							Active:         1,
+							Currency:       currency.Guid,
+							OwnerType:      sql.NullInt32{int32(gnucash.OwnerTypeEmployee), true},
+							OwnerGuid:      sql.NullString{vg.employeeGuid, true},
+							ChargeAmtNum:   sql.NullInt64{0, true},
+							ChargeAmtDenom: sql.NullInt64{1, true},
+						},
+					}
+					if err := book.AddInvoice(voucher); err != nil {
+						fatal(err)
+					}
+					recordRun(book, voucher, runId, runDate)
+					recordPeriod(book, voucher, vg.period)
+
+					fmt.Printf("VOUCHER %s for employee %s\n", voucher.Id, voucher.GetEmployee().Username)
+				}
+
+				for _, entry := range vg.entries {
 					amt := big.NewRat(entry.BPriceNum.Int64, entry.BPriceDenom.Int64)
 					fmt.Printf(" * %50s %s\n", entry.Description.String, amt.String())
-					voucher.AddEntry(entry)
+					if err := voucher.AddEntry(entry); err != nil {
+						fatal(err)
+					}
+					recordVoucherEntry(book, voucher, entry, voucherSources[entry])
+					if appended {
+						recordAddedEntry(book, voucher, entry, runId)
+					}
+
+					username := voucher.GetEmployee().Username
+					if charged[username] == nil {
+						charged[username] = make(map[string]*big.Rat)
+					}
+					billId := book.GetInvoiceByGUID(voucherSources[entry]).Id
+					if charged[username][billId] == nil {
+						charged[username][billId] = new(big.Rat)
+					}
+					// Credit note quantities are negated
+					gross, err := entry.GetGross()
+					if err != nil {
+						fatal(err)
+					}
+					charged[username][billId].Sub(charged[username][billId], gross)
 				}
 
-				// Don't post vouchers as I might want to edit them manually first
-				//voucher.Post(ap)
+				// Vouchers are left unposted by default so they can be edited manually first
+				if postVouchers {
+					if err := voucher.Post(employeeAcc, voucher.DateOpened.String, ""); err != nil {
+						fatal(err)
+					}
+				}
+			}
+
+			if len(charged) > 0 {
+				printChargedSummary(charged)
 			}
 		},
 	}
 )
+
+// Prints the amounts charged to each employee per bill and in total
+func printChargedSummary(charged map[string]map[string]*big.Rat) {
+	fmt.Println("CHARGED TO EMPLOYEES")
+
+	usernames := []string{}
+	for username := range charged {
+		usernames = append(usernames, username)
+	}
+	sort.Strings(usernames)
+
+	for _, username := range usernames {
+		billIds := []string{}
+		for billId := range charged[username] {
+			billIds = append(billIds, billId)
+		}
+		sort.Strings(billIds)
+
+		total := new(big.Rat)
+		for _, billId := range billIds {
+			amt := charged[username][billId]
+			fmt.Printf(" %-20s BILL %-10s %12s\n", username, billId, amt.FloatString(2))
+			total.Add(total, amt)
+		}
+		fmt.Printf(" %-20s %-15s %12s\n", username, "TOTAL", total.FloatString(2))
+	}
+}
+
+// Entries and payments that end up on one generated bill
+type billGroup struct {
+	periodEnd    time.Time
+	entries      []*gnucash.Entry
+	splits       []*gnucash.Split
+	payments     []*gnucash.Split
+	rules        []string
+	dates        []time.Time
+	voucherItems []*gnucash.Entry
+}
+
+// Expense voucher entries of one employee and billing period
+type voucherGroup struct {
+	employeeGuid string
+	period       string
+	periodEnd    time.Time
+	entries      []*gnucash.Entry
+	dates        []time.Time
+}
+
+// Returns an invoice entry re-billing split s to a customer according to rule r
+func invoiceEntry(s *gnucash.Split, r *monthlyRule) *gnucash.Entry {
+	date := gnucash.GetCurrentTimeString()
+	if s.Transaction.PostDate.Valid {
+		date = s.Transaction.PostDate.String
+	}
+
+	// Costs are debits (positive) in debit-normal accounts like expense or
+	// prepaid-cost asset accounts and credits (negative) in credit-normal ones
+	// like the card or payable accounts they were charged to, while the A/R
+	// side charges the customer with a positive price. Refunds keep their
+	// opposite sign and become credit lines.
+	price := big.NewRat(s.ValueNum, s.ValueDenom)
+	if !s.Account.IsDebitNormal() {
+		price.Neg(price)
+	}
+
+	quantity := big.NewRat(1, 1)
+	if r.CustomerShare != nil {
+		quantity = r.CustomerShare
+	}
+
+	e := &gnucash.Entry{
+		DbEntry: gnucash.DbEntry{
+			Date:          date,
+			DateEntered:   sql.NullString{gnucash.GetCurrentTimeString(), true},
+			Description:   sql.NullString{r.Description, true},
+			QuantityNum:   sql.NullInt64{quantity.Num().Int64(), true},
+			QuantityDenom: sql.NullInt64{quantity.Denom().Int64(), true},
+			IAcct:         sql.NullString{r.Account.Guid, true},
+			IPriceNum:     sql.NullInt64{price.Num().Int64(), true},
+			IPriceDenom:   sql.NullInt64{price.Denom().Int64(), true},
+		},
+	}
+
+	// Re-billed costs are net amounts, taxes are added on top
+	if r.TaxTable != nil {
+		e.SetInvoiceTaxTable(r.TaxTable, false)
+	}
+
+	return e
+}
+
+// Returns the most recently opened unposted document (bill or voucher) of the
+// owner with GUID ownerGuid that belongs to billing period period, or nil if
+// there is none
+func findOpenDocument(book *gnucash.Book, ownerGuid string, creditNote bool, period string,
+	endDateTime time.Time) *gnucash.Invoice {
+	var found *gnucash.Invoice
+	for _, i := range book.GetInvoices() {
+		if i.OwnerGuid.String != ownerGuid || i.DatePosted.Valid || i.Active == 0 ||
+			i.IsCreditNote != creditNote {
+			continue
+		}
+
+		p, ok := documentPeriod(book, i, endDateTime)
+		if !ok || p != period {
+			continue
+		}
+
+		if found == nil || i.DateOpened.String > found.DateOpened.String {
+			found = i
+		}
+	}
+
+	return found
+}
+
+// Returns the billing period of a document: the one recorded when it was
+// generated, otherwise the one its open date falls in. Returns false if the
+// period can't be determined.
+func documentPeriod(book *gnucash.Book, i *gnucash.Invoice, endDateTime time.Time) (string, bool) {
+	if s := book.GetSlot(i.Guid, generatedPeriodSlot); s != nil {
+		return s.StringVal.String, true
+	}
+
+	// Periods of single transactions are only known for generated documents
+	if groupBy == "transaction" || !i.DateOpened.Valid {
+		return "", false
+	}
+
+	d, err := time.Parse("2006-01-02 15:04:05", i.DateOpened.String)
+	if err != nil {
+		return "", false
+	}
+
+	period, _ := billingPeriod(nil, d, endDateTime)
+	return period, true
+}
+
+// Returns true if the txn of split s has a split in the credit card account of
+// employee e
+func paidByCard(s *gnucash.Split, e *gnucash.Employee) bool {
+	if !e.CCardGuid.Valid {
+		return false
+	}
+
+	for _, ts := range s.Transaction.Splits {
+		if ts.AccountGuid == e.CCardGuid.String {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Returns true if a split with this action belongs to the posting txn of a
+// document or to an assigned payment
+func isBusinessAction(action string) bool {
+	switch action {
+	case "Payment", "Bill", "Invoice", "Expense", "Credit Note":
+		return true
+	}
+
+	return false
+}
+
+// Returns the key of the billing period a payment split with date d belongs
+// to, and the (exclusive) end of that period. Keys sort chronologically.
+func billingPeriod(s *gnucash.Split, d time.Time, endDateTime time.Time) (string, time.Time) {
+	day := time.Date(d.Year(), d.Month(), d.Day(), 0, 0, 0, 0, time.UTC)
+
+	var key string
+	var end time.Time
+	switch groupBy {
+	case "month":
+		start := day.AddDate(0, 0, 1-day.Day())
+		key, end = start.Format("2006-01"), start.AddDate(0, 1, 0)
+	case "week":
+		// Weeks start on Monday
+		start := day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+		key, end = start.Format("2006-01-02"), start.AddDate(0, 0, 7)
+	case "transaction":
+		key, end = d.Format("2006-01-02 15:04:05")+" "+s.Transaction.Guid, day.AddDate(0, 0, 1)
+	default:
+		return "", endDateTime
+	}
+
+	// Last period ends early if an end date was given
+	if !endDateTime.IsZero() && endDateTime.Before(end) {
+		end = endDateTime
+	}
+
+	return key, end
+}
+
+// Returns the split of s's transaction that has to be assigned as payment to a
+// bill posted to payable account ap. This is s itself if it's in ap, another
+// split in ap or, for transactions with only two splits, the other split
+// (which assignPayment moves to ap). Returns nil if there is no such split.
+func paymentSplit(s *gnucash.Split, ap *gnucash.Account) *gnucash.Split {
+	if s.Account == ap {
+		return s
+	}
+
+	for _, o := range s.Transaction.Splits {
+		if o.Account == ap {
+			return o
+		}
+	}
+
+	if len(s.Transaction.Splits) == 2 {
+		for _, o := range s.Transaction.Splits {
+			if o != s {
+				return o
+			}
+		}
+	}
+
+	return nil
+}
+
+// Returns the date a generated document is opened and posted at according to
+// the date strategy. dates are the dates of the matched payments, periodEnd is
+// the (exclusive) end of the billing period or zero if there is none.
+func documentDate(dates []time.Time, periodEnd time.Time) string {
+	if len(dates) == 0 || dateStrategy == "today" {
+		return gnucash.GetDateString(time.Now())
+	}
+
+	earliest := dates[0]
+	latest := dates[0]
+	for _, d := range dates {
+		if d.Before(earliest) {
+			earliest = d
+		}
+		if d.After(latest) {
+			latest = d
+		}
+	}
+
+	switch dateStrategy {
+	case "earliest":
+		return gnucash.GetDateString(earliest)
+	case "latest":
+		return gnucash.GetDateString(latest)
+	case "period-end":
+		if periodEnd.IsZero() {
+			// Without an explicit period, bill until the end of the month
+			periodEnd = time.Date(latest.Year(), latest.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+		}
+		return gnucash.GetDateString(periodEnd.AddDate(0, 0, -1))
+	}
+
+	return gnucash.GetDateString(time.Now())
+}
+
+// Creates the vendors of configs that name a vendor not in the book (only with
+// --create-vendors), asking for confirmation first
+func createMissingVendors(book *gnucash.Book, configs []*monthlyConfig) {
+	names := []string{}
+	seen := make(map[string]bool)
+	for _, c := range configs {
+		if c.NewVendor != "" && !seen[c.NewVendor] {
+			names = append(names, c.NewVendor)
+			seen[c.NewVendor] = true
+		}
+	}
+
+	if len(names) == 0 {
+		return
+	}
+
+	fmt.Printf("Vendors not found in book: %s\n", strings.Join(names, ", "))
+	if !confirm("Create them?") {
+		fatal("Aborted, missing vendors have not been created")
+	}
+
+	vendors := make(map[string]*gnucash.Vendor)
+	for _, name := range names {
+		v := &gnucash.Vendor{
+			DbVendor: gnucash.DbVendor{
+				Name:   name,
+				Active: 1,
+			},
+		}
+		if err := book.AddVendor(v); err != nil {
+			fatal(err)
+		}
+		vendors[name] = v
+
+		fmt.Printf("Created vendor %s %s\n", v.Id, v.Name)
+	}
+
+	for _, c := range configs {
+		if c.NewVendor != "" {
+			c.Vendor = vendors[c.NewVendor]
+		}
+	}
+}
+
+// Returns the ID the next document gets, given the function returning the next
+// ID of its type (e.g. book.GetNextBillId)
+func documentId(next func() (string, error)) string {
+	id, err := next()
+	if err != nil {
+		fatal(err)
+	}
+
+	return id
+}
+
+// Asks a yes/no question on the terminal, anything but 'y' or 'yes' means no
+func confirm(question string) bool {
+	fmt.Printf("%s [y/N] ", question)
+
+	answer, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && answer == "" {
+		return false
+	}
+
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return true
+	}
+
+	return false
+}
@@ -0,0 +1,160 @@
+package cmd
+
+import (
+	"database/sql"
+	"math/big"
+	"testing"
+	"time"
+
+	"bvorhofer.com/matchmaker/gnucash"
+)
+
+func TestBillingPeriod(t *testing.T) {
+	day := func(s string) time.Time {
+		d, err := time.Parse("2006-01-02 15:04:05", s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return d
+	}
+
+	s := &gnucash.Split{
+		Transaction: &gnucash.Transaction{
+			DbTransaction: gnucash.DbTransaction{Guid: "tx01000000000000000000000000001"},
+		},
+	}
+
+	tests := []struct {
+		name    string
+		groupBy string
+		date    string
+		end     string // end date of the run, "" for none
+		wantKey string
+		wantEnd string
+	}{
+		{"none", "none", "2021-09-05 10:59:00", "", "", ""},
+		{"none with end date", "none", "2021-09-05 10:59:00", "2021-10-01 00:00:00", "", "2021-10-01 00:00:00"},
+		{"month", "month", "2021-09-05 10:59:00", "", "2021-09", "2021-10-01 00:00:00"},
+		{"month on first day", "month", "2021-09-01 00:00:00", "", "2021-09", "2021-10-01 00:00:00"},
+		{"month on last day", "month", "2021-09-30 23:59:59", "", "2021-09", "2021-10-01 00:00:00"},
+		{"december", "month", "2021-12-24 10:59:00", "", "2021-12", "2022-01-01 00:00:00"},
+		{"month ending early", "month", "2021-09-05 10:59:00", "2021-09-20 00:00:00", "2021-09", "2021-09-20 00:00:00"},
+		{"month ending after end date", "month", "2021-09-05 10:59:00", "2021-11-01 00:00:00", "2021-09", "2021-10-01 00:00:00"},
+		{"week", "week", "2021-09-08 10:59:00", "", "2021-09-06", "2021-09-13 00:00:00"},
+		{"week on monday", "week", "2021-09-06 00:00:00", "", "2021-09-06", "2021-09-13 00:00:00"},
+		{"week on sunday", "week", "2021-09-12 23:59:59", "", "2021-09-06", "2021-09-13 00:00:00"},
+		{"week across months", "week", "2021-10-01 10:59:00", "", "2021-09-27", "2021-10-04 00:00:00"},
+		{"transaction", "transaction", "2021-09-05 10:59:00", "",
+			"2021-09-05 10:59:00 tx01000000000000000000000000001", "2021-09-06 00:00:00"},
+	}
+
+	defer func(g string) { groupBy = g }(groupBy)
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			groupBy = tc.groupBy
+
+			var end, wantEnd time.Time
+			if tc.end != "" {
+				end = day(tc.end)
+			}
+			if tc.wantEnd != "" {
+				wantEnd = day(tc.wantEnd)
+			}
+
+			key, periodEnd := billingPeriod(s, day(tc.date), end)
+			if key != tc.wantKey {
+				t.Errorf("key %q, want %q", key, tc.wantKey)
+			}
+			if !periodEnd.Equal(wantEnd) {
+				t.Errorf("end %s, want %s", periodEnd, wantEnd)
+			}
+		})
+	}
+}
+
+func TestBillingPeriodKeysSortChronologically(t *testing.T) {
+	defer func(g string) { groupBy = g }(groupBy)
+
+	dates := []time.Time{
+		time.Date(2021, 9, 26, 0, 0, 0, 0, time.UTC),
+		time.Date(2021, 10, 1, 0, 0, 0, 0, time.UTC),
+		time.Date(2021, 12, 31, 0, 0, 0, 0, time.UTC),
+		time.Date(2022, 1, 3, 0, 0, 0, 0, time.UTC),
+	}
+
+	for _, g := range []string{"month", "week"} {
+		groupBy = g
+
+		prev := ""
+		for _, d := range dates {
+			key, _ := billingPeriod(nil, d, time.Time{})
+			if key <= prev {
+				t.Errorf("%s: key %q of %s doesn't sort after %q", g, key, d.Format("2006-01-02"), prev)
+			}
+			prev = key
+		}
+	}
+}
+
+func TestInvoiceEntry(t *testing.T) {
+	income := &gnucash.Account{DbAccount: gnucash.DbAccount{Guid: "rbc00000000000000000000000000001"}}
+
+	tests := []struct {
+		name        string
+		accountType string // of the source split
+		value       int64  // in cents
+		share       string // "" for all of it
+		price       string
+	}{
+		{"expense", "EXPENSE", 5000, "", "50"},
+		{"expense refund", "EXPENSE", -2000, "", "-20"},
+		{"prepaid costs", "ASSET", 12000, "", "120"},
+		{"prepaid costs refund", "ASSET", -12000, "", "-120"},
+		{"credit card", "CREDIT", -5000, "", "50"},
+		{"credit card refund", "CREDIT", 2000, "", "-20"},
+		{"payable", "PAYABLE", -5000, "", "50"},
+		{"liability", "LIABILITY", -5000, "", "50"},
+		{"share", "EXPENSE", 5000, "1/2", "50"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			s := &gnucash.Split{
+				DbSplit: gnucash.DbSplit{ValueNum: tc.value, ValueDenom: 100},
+				Account: &gnucash.Account{DbAccount: gnucash.DbAccount{
+					Type: sql.NullString{String: tc.accountType, Valid: true},
+				}},
+				Transaction: &gnucash.Transaction{DbTransaction: gnucash.DbTransaction{
+					PostDate: sql.NullString{String: "2021-09-05 10:59:00", Valid: true},
+				}},
+			}
+			r := &monthlyRule{Description: "Electricity (shared)", Account: income}
+			if tc.share != "" {
+				r.CustomerShare, _ = new(big.Rat).SetString(tc.share)
+			}
+
+			e := invoiceEntry(s, r)
+
+			price := big.NewRat(e.IPriceNum.Int64, e.IPriceDenom.Int64)
+			if price.RatString() != tc.price {
+				t.Errorf("price %s, want %s", price.RatString(), tc.price)
+			}
+
+			// Shares are billed as quantity so the entry shows the full price
+			quantity := big.NewRat(e.QuantityNum.Int64, e.QuantityDenom.Int64)
+			want := tc.share
+			if want == "" {
+				want = "1"
+			}
+			if quantity.RatString() != want {
+				t.Errorf("quantity %s, want %s", quantity.RatString(), want)
+			}
+
+			if e.Date != s.Transaction.PostDate.String || e.IAcct.String != income.Guid {
+				t.Errorf("entry of %s to %s, want %s to %s", e.Date, e.IAcct.String,
+					s.Transaction.PostDate.String, income.Guid)
+			}
+		})
+	}
+}
@@ -0,0 +1,586 @@
+package cmd
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"bvorhofer.com/matchmaker/gnucash"
+	"gopkg.in/yaml.v2"
+)
+
+// Bill generation config for one vendor (or invoice generation config for one
+// customer), loaded from a YAML or a (legacy) CSV config file
+type monthlyConfig struct {
+	File              string
+	Vendor            *gnucash.Vendor
+	Customer          *gnucash.Customer
+	Job               *gnucash.Job
+	NewVendor         string
+	PayableAccount    *gnucash.Account
+	ReceivableAccount *gnucash.Account
+	Terms             *gnucash.BillTerm
+	SourceAccounts    []*gnucash.Account
+	Rules             []*monthlyRule
+}
+
+// Rule for turning a matched payment into a bill entry (or a matched split into
+// an invoice entry)
+type monthlyRule struct {
+	Name          string
+	Condition     splitCondition
+	Description   string
+	Account       *gnucash.Account
+	TaxTable      *gnucash.TaxTable
+	Shares        []employeeShare
+	CustomerShare *big.Rat
+}
+
+// Share of a bill entry charged to an employee via expense voucher
+type employeeShare struct {
+	Employee *gnucash.Employee
+	Share    *big.Rat
+}
+
+// Unparsed share of a rule, key is an employee username or '@' followed by a
+// group name
+type shareSpec struct {
+	key   string
+	value string
+}
+
+// YAML representation of a config file, e.g.
+//
+//	vendor: SWM
+//	payable-account: Liabilities:Accounts Payable
+//	source-accounts:
+//	  - Liabilities:Accounts Payable
+//	  - Liabilities:Credit Card
+//	  - Assets:Bank:*
+//	groups:
+//	  flat: [chris, alex, sam]
+//	rules:
+//	  - name: electricity
+//	    match:
+//	      description: Strom
+//	    description: Electricity
+//	    account: Expenses:Utilities
+//	    shares:
+//	      chris: 25%
+//	      "@flat": rest
+//
+// A tax table (e.g. 'VAT 19%') can be given for all rules and/or per rule
+// ('none' for no taxes). Matched payments include the taxes, re-billed costs of
+// customer configs get them added on top. Billing terms (e.g. 'Net 30') given
+// by terms determine the due dates of generated documents instead of the
+// default terms of the vendor or customer.
+//
+// Customer configs name a customer instead of a vendor and re-bill the matched
+// splits (optionally only a share of them) to the customer, e.g.
+//
+//	customer: ACME
+//	job: Office Move
+//	source-accounts: [Expenses:Utilities]
+//	rules:
+//	  - match:
+//	      description: Strom
+//	    description: Electricity (shared)
+//	    account: Income:Rebilled Costs
+//	    share: 50%
+type yamlConfig struct {
+	Vendor            string
+	VendorId          string `yaml:"vendor-id"`
+	Customer          string
+	CustomerId        string `yaml:"customer-id"`
+	Job               string
+	PayableAccount    string   `yaml:"payable-account"`
+	ReceivableAccount string   `yaml:"receivable-account"`
+	SourceAccounts    []string `yaml:"source-accounts"`
+	TaxTable          string   `yaml:"tax-table"`
+	Terms             string
+	Groups            map[string][]string
+	Rules             []yamlRule
+}
+
+type yamlRule struct {
+	Name        string
+	Match       yamlCondition
+	Description string
+	Account     string
+	TaxTable    string `yaml:"tax-table"`
+	Shares      map[string]string
+	Share       string
+}
+
+// Returns true if path is a config file monthly should process
+func isMonthlyConfigFile(path string) bool {
+	switch filepath.Ext(path) {
+	case ".csv", ".yaml", ".yml":
+		return true
+	}
+
+	return false
+}
+
+// Loads a config file and validates it against the book. All problems found
+// are returned, not just the first one.
+func loadMonthlyConfig(book *gnucash.Book, path string) (*monthlyConfig, []error) {
+	if filepath.Ext(path) == ".csv" {
+		return loadCSVMonthlyConfig(book, path)
+	}
+
+	return loadYAMLMonthlyConfig(book, path)
+}
+
+func loadYAMLMonthlyConfig(book *gnucash.Book, path string) (*monthlyConfig, []error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, []error{fmt.Errorf("%s: %s", path, err)}
+	}
+
+	var yc yamlConfig
+	err = yaml.UnmarshalStrict(data, &yc)
+	if err != nil {
+		return nil, []error{fmt.Errorf("%s: %s", path, err)}
+	}
+
+	errs := []error{}
+	fail := func(format string, a ...interface{}) {
+		errs = append(errs, fmt.Errorf(path+": "+format, a...))
+	}
+
+	c := &monthlyConfig{File: filepath.Base(path)}
+
+	owners := 0
+	for _, o := range []string{yc.Vendor, yc.VendorId, yc.Customer, yc.CustomerId} {
+		if o != "" {
+			owners++
+		}
+	}
+
+	switch {
+	case owners > 1:
+		fail("only one of vendor, vendor-id, customer and customer-id may be given")
+	case yc.Vendor != "":
+		c.Vendor = findVendor(book, yc.Vendor)
+		if c.Vendor == nil {
+			if createVendors {
+				c.NewVendor = yc.Vendor
+			} else {
+				fail("unable to find vendor '%s'", yc.Vendor)
+			}
+		}
+	case yc.VendorId != "":
+		c.Vendor = book.GetVendorByID(yc.VendorId)
+		if c.Vendor == nil {
+			fail("unable to find vendor with ID '%s'", yc.VendorId)
+		}
+	case yc.Customer != "":
+		c.Customer = book.GetCustomerByName(yc.Customer)
+		if c.Customer == nil {
+			fail("unable to find customer '%s'", yc.Customer)
+		}
+	case yc.CustomerId != "":
+		c.Customer = book.GetCustomerByID(yc.CustomerId)
+		if c.Customer == nil {
+			fail("unable to find customer with ID '%s'", yc.CustomerId)
+		}
+	default:
+		fail("no vendor or customer given")
+	}
+
+	customer := yc.Customer != "" || yc.CustomerId != ""
+
+	// Bill matched costs to a job of the vendor or customer
+	if yc.Job != "" {
+		c.Job = book.GetJobByName(yc.Job)
+		switch {
+		case c.Job == nil:
+			fail("unable to find job '%s'", yc.Job)
+		case c.NewVendor != "":
+			fail("job '%s' doesn't belong to vendor '%s'", yc.Job, c.NewVendor)
+		case c.Vendor != nil && (c.Job.GetVendor() == nil || c.Job.GetVendor() != c.Vendor):
+			fail("job '%s' doesn't belong to vendor '%s'", yc.Job, c.Vendor.Name)
+		case c.Customer != nil && (c.Job.GetCustomer() == nil || c.Job.GetCustomer() != c.Customer):
+			fail("job '%s' doesn't belong to customer '%s'", yc.Job, c.Customer.Name)
+		}
+	}
+	if customer {
+		if yc.PayableAccount != "" {
+			fail("payable-account can't be given for customers")
+		}
+
+		if len(yc.SourceAccounts) == 0 {
+			fail("source-accounts are required for customers")
+		}
+	} else if yc.ReceivableAccount != "" {
+		fail("receivable-account can only be given for customers")
+	}
+
+	if yc.ReceivableAccount != "" {
+		c.ReceivableAccount = book.GetAccountByPath(yc.ReceivableAccount)
+		if c.ReceivableAccount == nil {
+			fail("unable to find receivable account '%s'", yc.ReceivableAccount)
+		}
+	}
+
+	if yc.PayableAccount != "" {
+		c.PayableAccount = book.GetAccountByPath(yc.PayableAccount)
+		if c.PayableAccount == nil {
+			fail("unable to find payable account '%s'", yc.PayableAccount)
+		}
+	}
+
+	// Accounts to search for payments, a trailing ':*' includes sub-accounts
+	for _, path := range yc.SourceAccounts {
+		subtree := strings.HasSuffix(path, ":*")
+		acc := book.GetAccountByPath(strings.TrimSuffix(path, ":*"))
+		if acc == nil {
+			fail("unable to find source account '%s'", path)
+			continue
+		}
+
+		c.SourceAccounts = append(c.SourceAccounts, acc)
+		if subtree {
+			c.SourceAccounts = append(c.SourceAccounts, acc.GetDescendants()...)
+		}
+	}
+
+	// Default tax table of all rules
+	var taxTable *gnucash.TaxTable
+	if yc.TaxTable != "" {
+		taxTable = findTaxTable(book, yc.TaxTable, fail)
+	}
+
+	// Billing terms override the default terms of the vendor or customer
+	if yc.Terms != "" {
+		c.Terms = book.GetBillTermByName(yc.Terms)
+		if c.Terms == nil {
+			fail("unable to find billing terms '%s'", yc.Terms)
+		}
+	}
+
+	if len(yc.Rules) == 0 {
+		fail("no rules given")
+	}
+
+	names := make(map[string]bool)
+	for n, yr := range yc.Rules {
+		r := &monthlyRule{
+			Name:        yr.Name,
+			Description: yr.Description,
+		}
+		if r.Name == "" {
+			r.Name = strconv.Itoa(n + 1)
+		}
+
+		ruleFail := func(format string, a ...interface{}) {
+			fail("rule '%s': "+format, append([]interface{}{r.Name}, a...)...)
+		}
+
+		if names[r.Name] {
+			ruleFail("duplicate rule name")
+		}
+		names[r.Name] = true
+
+		r.Condition = parseCondition(book, yr.Match, ruleFail)
+
+		if yr.Description == "" {
+			ruleFail("no entry description given")
+		}
+
+		r.Account = book.GetAccountByPath(yr.Account)
+		if r.Account == nil {
+			ruleFail("unable to find account '%s'", yr.Account)
+		}
+
+		r.TaxTable = taxTable
+		if yr.TaxTable != "" {
+			r.TaxTable = findTaxTable(book, yr.TaxTable, ruleFail)
+		}
+
+		if customer {
+			if len(yr.Shares) > 0 {
+				ruleFail("employee shares can't be given for customers")
+			}
+
+			if yr.Share != "" {
+				share, err := parseShareValue(yr.Share)
+				if err != nil {
+					ruleFail("%s", err)
+				}
+				r.CustomerShare = share
+			}
+		} else if yr.Share != "" {
+			ruleFail("share can only be given for customers, use shares for employees")
+		}
+
+		// Sort by key so vouchers are generated in a stable order
+		specs := []shareSpec{}
+		for key, value := range yr.Shares {
+			specs = append(specs, shareSpec{key, value})
+		}
+		sort.Slice(specs, func(i, j int) bool { return specs[i].key < specs[j].key })
+
+		var shareErrs []error
+		r.Shares, shareErrs = parseShares(book, yc.Groups, specs)
+		for _, err := range shareErrs {
+			ruleFail("%s", err)
+		}
+
+		c.Rules = append(c.Rules, r)
+	}
+
+	return c, errs
+}
+
+// Returns the tax table with the given name, nil for 'none'
+func findTaxTable(book *gnucash.Book, name string,
+	fail func(format string, a ...interface{})) *gnucash.TaxTable {
+	if name == "none" {
+		return nil
+	}
+
+	t := book.GetTaxTableByName(name)
+	if t == nil {
+		fail("unable to find tax table '%s'", name)
+	}
+
+	return t
+}
+
+// Returns the vendor with the given name or, if there is none, the vendor with
+// the given alias
+func findVendor(book *gnucash.Book, name string) *gnucash.Vendor {
+	if v := book.GetVendorByName(name); v != nil {
+		return v
+	}
+
+	return book.GetVendorByAlias(name)
+}
+
+// Loads a headerless CSV config whose vendor is given by the file name. Columns
+// are: SPLIT MEMO REGEX, TXN DESC REGEX, ENTRY DESCR, DEST ACCT, followed by
+// alternating employee usernames and num/denom shares.
+func loadCSVMonthlyConfig(book *gnucash.Book, path string) (*monthlyConfig, []error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, []error{fmt.Errorf("%s: %s", path, err)}
+	}
+
+	r := csv.NewReader(strings.NewReader(string(data)))
+	r.FieldsPerRecord = -1
+	lines, err := r.ReadAll()
+	if err != nil {
+		return nil, []error{fmt.Errorf("%s: %s", path, err)}
+	}
+
+	errs := []error{}
+	fail := func(format string, a ...interface{}) {
+		errs = append(errs, fmt.Errorf(path+": "+format, a...))
+	}
+
+	c := &monthlyConfig{File: filepath.Base(path)}
+
+	// Find vendor based on filename
+	vendorName := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	c.Vendor = findVendor(book, vendorName)
+	if c.Vendor == nil {
+		if createVendors {
+			c.NewVendor = vendorName
+		} else {
+			fail("unable to find vendor '%s'", vendorName)
+		}
+	}
+
+	for n, m := range lines {
+		line := n + 1
+		if len(m) < 4 {
+			fail("line %d: expected at least 4 columns", line)
+			continue
+		}
+
+		r := &monthlyRule{
+			Name:        strconv.Itoa(line),
+			Description: m[2],
+		}
+
+		var tc textCondition
+		var err error
+		if m[0] != "" {
+			tc.memo, err = regexp.Compile(m[0])
+			if err != nil {
+				fail("line %d: error in regex '%s': %s", line, m[0], err)
+			}
+		}
+
+		if m[1] != "" {
+			tc.desc, err = regexp.Compile(m[1])
+			if err != nil {
+				fail("line %d: error in regex '%s': %s", line, m[1], err)
+			}
+		}
+		r.Condition = tc
+
+		r.Account = book.GetAccountByPath(m[3])
+		if r.Account == nil {
+			fail("line %d: unable to find account '%s'", line, m[3])
+		}
+
+		// Find employees for reimbursements (optional)
+		specs := []shareSpec{}
+		for j := 4; j < len(m)-1; j += 2 {
+			if m[j] == "" {
+				break
+			}
+
+			specs = append(specs, shareSpec{m[j], m[j+1]})
+		}
+
+		var shareErrs []error
+		r.Shares, shareErrs = parseShares(book, nil, specs)
+		for _, err := range shareErrs {
+			fail("line %d: %s", line, err)
+		}
+
+		c.Rules = append(c.Rules, r)
+	}
+
+	return c, errs
+}
+
+// Parses and validates the shares of a rule. Keys are employee usernames, or
+// '@' followed by the name of a group to split a share equally among its
+// members. Values are fractions ('1/3'), decimals ('0.25'), percentages ('25%')
+// or 'rest' for whatever the other shares leave. Shares must add up to at most
+// 1, each employee may only be charged once.
+func parseShares(book *gnucash.Book, groups map[string][]string,
+	specs []shareSpec) ([]employeeShare, []error) {
+	errs := []error{}
+
+	type resolved struct {
+		employees []*gnucash.Employee
+		share     *big.Rat
+	}
+
+	total := new(big.Rat)
+	var rest *resolved
+	rests := 0
+	seen := make(map[*gnucash.Employee]bool)
+	rs := []*resolved{}
+	for _, spec := range specs {
+		usernames := []string{spec.key}
+		if strings.HasPrefix(spec.key, "@") {
+			var ok bool
+			usernames, ok = groups[strings.TrimPrefix(spec.key, "@")]
+			if !ok || len(usernames) == 0 {
+				errs = append(errs, fmt.Errorf("unknown or empty group '%s'", spec.key))
+				continue
+			}
+		}
+
+		r := &resolved{}
+		for _, username := range usernames {
+			e := book.GetEmployeeByUsername(username)
+			if e == nil {
+				errs = append(errs, fmt.Errorf("unable to find employee '%s'", username))
+				continue
+			}
+
+			if e.Active == 0 {
+				errs = append(errs, fmt.Errorf("employee '%s' is inactive", username))
+				continue
+			}
+
+			if seen[e] {
+				errs = append(errs, fmt.Errorf("employee '%s' is given more than one share", username))
+				continue
+			}
+			seen[e] = true
+
+			r.employees = append(r.employees, e)
+		}
+
+		if spec.value == "rest" {
+			rests++
+			if rests > 1 {
+				errs = append(errs, fmt.Errorf("only one remainder share may be given"))
+				continue
+			}
+			rest = r
+		} else {
+			share, err := parseShareValue(spec.value)
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+
+			r.share = share
+			total.Add(total, share)
+		}
+
+		if len(r.employees) > 0 {
+			rs = append(rs, r)
+		}
+	}
+
+	one := big.NewRat(1, 1)
+	if total.Cmp(one) > 0 {
+		errs = append(errs, fmt.Errorf("shares add up to %s, more than 1", total.RatString()))
+	}
+
+	if rest != nil {
+		rest.share = new(big.Rat).Sub(one, total)
+		if rest.share.Sign() <= 0 {
+			errs = append(errs, fmt.Errorf("nothing left for remainder share"))
+		}
+	}
+
+	if len(errs) > 0 {
+		return nil, errs
+	}
+
+	shares := []employeeShare{}
+	for _, r := range rs {
+		// Split share equally among group members
+		share := new(big.Rat).Mul(r.share, big.NewRat(1, int64(len(r.employees))))
+		for _, e := range r.employees {
+			shares = append(shares, employeeShare{Employee: e, Share: share})
+		}
+	}
+
+	return shares, nil
+}
+
+// Parses a share given as fraction, decimal or percentage, it has to lie
+// within (0, 1]
+func parseShareValue(value string) (*big.Rat, error) {
+	v := strings.TrimSpace(value)
+	percent := strings.HasSuffix(v, "%")
+
+	share, ok := new(big.Rat).SetString(strings.TrimSuffix(v, "%"))
+	if !ok {
+		return nil, fmt.Errorf("invalid share '%s'", value)
+	}
+
+	if percent {
+		share.Mul(share, big.NewRat(1, 100))
+	}
+
+	if share.Sign() <= 0 || share.Cmp(big.NewRat(1, 1)) > 0 {
+		return nil, fmt.Errorf("share '%s' is not within (0, 1]", value)
+	}
+
+	return share, nil
+}
+
+// Returns true if the rule matches split s
+func (r *monthlyRule) Matches(s *gnucash.Split) bool {
+	return r.Condition.Matches(s)
+}
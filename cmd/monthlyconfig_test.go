@@ -0,0 +1,495 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"bvorhofer.com/matchmaker/gnucash"
+	"github.com/jmoiron/sqlx"
+)
+
+// Opens a book created from the gnucash package's schema and testdata/book.sql,
+// the book is closed when the test ends
+func openTestBook(t *testing.T) *gnucash.Book {
+	path := filepath.Join(t.TempDir(), "test.gnucash")
+
+	db, err := sqlx.Open("sqlite3", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	for _, f := range []string{"../gnucash/testdata/schema.sql", "testdata/book.sql"} {
+		query, err := os.ReadFile(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := db.Exec(string(query)); err != nil {
+			t.Fatalf("%s: %v", f, err)
+		}
+	}
+
+	book, err := gnucash.OpenBookFromSQLite(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if err := book.Close(); err != nil {
+			t.Error(err)
+		}
+	})
+
+	return book
+}
+
+// Writes a config file with the given name and content to a temp dir, returns
+// its path
+func writeConfig(t *testing.T, name string, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(strings.TrimLeft(content, "\n")), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+// Fails unless errs holds exactly one error per wanted substring (in order)
+func checkErrors(t *testing.T, errs []error, want ...string) {
+	t.Helper()
+
+	if len(errs) != len(want) {
+		t.Errorf("got %d errors, want %d: %v", len(errs), len(want), errs)
+		return
+	}
+
+	for n, err := range errs {
+		if !strings.Contains(err.Error(), want[n]) {
+			t.Errorf("error %q doesn't contain %q", err, want[n])
+		}
+	}
+}
+
+func TestLoadYAMLMonthlyConfig(t *testing.T) {
+	book := openTestBook(t)
+	path := writeConfig(t, "swm.yaml", `
+vendor: Stadtwerke
+payable-account: Liabilities:Accounts Payable
+source-accounts:
+  - Liabilities:Accounts Payable
+  - Assets:Bank:*
+groups:
+  flat: [alex, sam]
+rules:
+  - name: electricity
+    match:
+      description: Strom
+    description: Electricity
+    account: Expenses:Utilities
+    shares:
+      chris: 25%
+      "@flat": rest
+  - match:
+      memo: Wasser
+    description: Water
+    account: Expenses:Utilities
+`)
+
+	c, errs := loadMonthlyConfig(book, path)
+	checkErrors(t, errs)
+	if c == nil {
+		t.FailNow()
+	}
+
+	if c.File != "swm.yaml" {
+		t.Errorf("file %q, want swm.yaml", c.File)
+	}
+	if c.Vendor == nil || c.Vendor.Name != "SWM" {
+		t.Errorf("vendor %v, want SWM (found by alias)", c.Vendor)
+	}
+	if c.PayableAccount == nil || c.PayableAccount.Name.String != "Accounts Payable" {
+		t.Errorf("payable account %v, want Accounts Payable", c.PayableAccount)
+	}
+
+	// Bank:* includes Bank and its sub-accounts
+	names := []string{}
+	for _, a := range c.SourceAccounts {
+		names = append(names, a.Name.String)
+	}
+	if got := strings.Join(names, ","); got != "Accounts Payable,Bank,Checking,Savings" {
+		t.Errorf("source accounts %s", got)
+	}
+
+	if len(c.Rules) != 2 {
+		t.Fatalf("%d rules, want 2", len(c.Rules))
+	}
+
+	r := c.Rules[0]
+	if r.Name != "electricity" || r.Description != "Electricity" || r.Account.Name.String != "Utilities" {
+		t.Errorf("rule %s: %s to %s", r.Name, r.Description, r.Account.Name.String)
+	}
+	shares := []string{}
+	for _, s := range r.Shares {
+		shares = append(shares, s.Employee.Username+"="+s.Share.RatString())
+	}
+	if got := strings.Join(shares, ","); got != "alex=3/8,sam=3/8,chris=1/4" {
+		t.Errorf("shares %s", got)
+	}
+
+	// Unnamed rules are named after their position
+	if r := c.Rules[1]; r.Name != "2" || len(r.Shares) != 0 {
+		t.Errorf("rule %s with %d shares, want rule 2 without shares", r.Name, len(r.Shares))
+	}
+}
+
+func TestLoadYAMLMonthlyConfigCustomer(t *testing.T) {
+	book := openTestBook(t)
+	path := writeConfig(t, "acme.yaml", `
+customer: ACME
+receivable-account: Assets:Accounts Receivable
+source-accounts: [Expenses:Utilities]
+rules:
+  - match:
+      description: Strom
+    description: Electricity (shared)
+    account: Income:Rebilled Costs
+    share: 50%
+`)
+
+	c, errs := loadMonthlyConfig(book, path)
+	checkErrors(t, errs)
+	if c == nil {
+		t.FailNow()
+	}
+
+	if c.Vendor != nil || c.Customer == nil || c.Customer.Name != "ACME" {
+		t.Errorf("vendor %v, customer %v, want customer ACME", c.Vendor, c.Customer)
+	}
+	if c.ReceivableAccount == nil || c.ReceivableAccount.Name.String != "Accounts Receivable" {
+		t.Errorf("receivable account %v", c.ReceivableAccount)
+	}
+	if len(c.Rules) != 1 || c.Rules[0].CustomerShare == nil || c.Rules[0].CustomerShare.RatString() != "1/2" {
+		t.Errorf("rules %v, want one with customer share 1/2", c.Rules)
+	}
+}
+
+func TestLoadYAMLMonthlyConfigErrors(t *testing.T) {
+	rule := `
+rules:
+  - match:
+      description: Strom
+    description: Electricity
+    account: Expenses:Utilities
+`
+
+	tests := []struct {
+		name   string
+		config string
+		want   []string
+	}{
+		{"unknown field", "vendr: SWM\n" + rule, []string{"field vendr not found"}},
+		{"no owner", rule, []string{"no vendor or customer given"}},
+		{"vendor and customer", "vendor: SWM\ncustomer: ACME\n" + rule,
+			[]string{"only one of vendor, vendor-id, customer and customer-id",
+				"source-accounts are required for customers"}},
+		{"unknown vendor", "vendor: EWS\n" + rule, []string{"unable to find vendor 'EWS'"}},
+		{"unknown vendor ID", "vendor-id: '000042'\n" + rule, []string{"unable to find vendor with ID '000042'"}},
+		{"unknown customer", "customer: Initech\nsource-accounts: [Expenses]\n" + rule,
+			[]string{"unable to find customer 'Initech'"}},
+		{"unknown accounts", "vendor: SWM\npayable-account: Liabilities:AP\nsource-accounts: [Assets:Cash:*]\n" + rule,
+			[]string{"unable to find payable account 'Liabilities:AP'", "unable to find source account 'Assets:Cash:*'"}},
+		{"payable account for customer",
+			"customer: ACME\npayable-account: Liabilities:Accounts Payable\nsource-accounts: [Expenses]\n" + rule,
+			[]string{"payable-account can't be given for customers"}},
+		{"customer without source accounts", "customer: ACME\n" + rule,
+			[]string{"source-accounts are required for customers"}},
+		{"receivable account for vendor", "vendor: SWM\nreceivable-account: Assets:Accounts Receivable\n" + rule,
+			[]string{"receivable-account can only be given for customers"}},
+		{"unknown tax table", "vendor: SWM\ntax-table: VAT 19%\n" + rule,
+			[]string{"unable to find tax table 'VAT 19%'"}},
+		{"unknown terms", "vendor: SWM\nterms: Net 30\n" + rule, []string{"unable to find billing terms 'Net 30'"}},
+		{"no rules", "vendor: SWM\n", []string{"no rules given"}},
+		{"incomplete rule", "vendor: SWM\nrules:\n  - name: x\n",
+			[]string{"rule 'x': empty match condition", "rule 'x': no entry description given",
+				"rule 'x': unable to find account ''"}},
+		{"duplicate rule name", "vendor: SWM\nrules:\n" +
+			"  - {name: x, match: {memo: a}, description: A, account: Expenses:Utilities}\n" +
+			"  - {name: x, match: {memo: b}, description: B, account: Expenses:Utilities}\n",
+			[]string{"rule 'x': duplicate rule name"}},
+		{"customer share for vendor", "vendor: SWM\nrules:\n" +
+			"  - {match: {memo: a}, description: A, account: Expenses:Utilities, share: 50%}\n",
+			[]string{"rule '1': share can only be given for customers"}},
+		{"employee shares for customer", "customer: ACME\nsource-accounts: [Expenses]\nrules:\n" +
+			"  - {match: {memo: a}, description: A, account: Expenses:Utilities, shares: {chris: 50%}}\n",
+			[]string{"rule '1': employee shares can't be given for customers"}},
+		{"invalid customer share", "customer: ACME\nsource-accounts: [Expenses]\nrules:\n" +
+			"  - {match: {memo: a}, description: A, account: Expenses:Utilities, share: 150%}\n",
+			[]string{"rule '1': share '150%' is not within (0, 1]"}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			book := openTestBook(t)
+			_, errs := loadYAMLMonthlyConfig(book, writeConfig(t, "test.yaml", tc.config))
+			checkErrors(t, errs, tc.want...)
+		})
+	}
+}
+
+// All problems of a config are reported at once, not just the first one
+func TestLoadYAMLMonthlyConfigReportsAllErrors(t *testing.T) {
+	book := openTestBook(t)
+	path := writeConfig(t, "test.yaml", `
+vendor: EWS
+payable-account: Liabilities:AP
+rules:
+  - name: electricity
+    match:
+      description: "Strom("
+    description: Electricity
+    account: Expenses:Power
+    shares:
+      chris: 3/4
+      alex: 1/2
+  - match:
+      amount: 50
+      day: 32
+    description: Water
+    account: Expenses:Utilities
+`)
+
+	_, errs := loadYAMLMonthlyConfig(book, path)
+	checkErrors(t, errs,
+		"unable to find vendor 'EWS'",
+		"unable to find payable account 'Liabilities:AP'",
+		"rule 'electricity': invalid description regex",
+		"rule 'electricity': unable to find account 'Expenses:Power'",
+		"rule 'electricity': shares add up to 5/4, more than 1",
+		"rule '2': invalid day '32'",
+	)
+
+	for _, err := range errs {
+		if !strings.HasPrefix(err.Error(), path+": ") {
+			t.Errorf("error %q isn't prefixed with the config path", err)
+		}
+	}
+}
+
+func TestLoadYAMLMonthlyConfigCreateVendor(t *testing.T) {
+	defer func(c bool) { createVendors = c }(createVendors)
+	createVendors = true
+
+	book := openTestBook(t)
+	path := writeConfig(t, "ews.yaml", `
+vendor: EWS
+rules:
+  - match:
+      description: Strom
+    description: Electricity
+    account: Expenses:Utilities
+`)
+
+	c, errs := loadYAMLMonthlyConfig(book, path)
+	checkErrors(t, errs)
+	if c == nil || c.Vendor != nil || c.NewVendor != "EWS" {
+		t.Errorf("config %+v, want new vendor EWS", c)
+	}
+}
+
+func TestLoadCSVMonthlyConfig(t *testing.T) {
+	book := openTestBook(t)
+	path := writeConfig(t, "SWM.csv", `
+Strom,,Electricity,Expenses:Utilities,chris,1/4,alex,3/4
+,Wasser,Water,Expenses:Utilities
+`)
+
+	c, errs := loadMonthlyConfig(book, path)
+	checkErrors(t, errs)
+	if c == nil {
+		t.FailNow()
+	}
+
+	if c.Vendor == nil || c.Vendor.Name != "SWM" {
+		t.Errorf("vendor %v, want SWM (named by the file)", c.Vendor)
+	}
+	if c.PayableAccount != nil || len(c.SourceAccounts) != 0 {
+		t.Error("CSV configs use the default payable account")
+	}
+	if len(c.Rules) != 2 {
+		t.Fatalf("%d rules, want 2", len(c.Rules))
+	}
+
+	r := c.Rules[0]
+	if r.Name != "1" || r.Description != "Electricity" || r.Account.Name.String != "Utilities" {
+		t.Errorf("rule %s: %s to %s", r.Name, r.Description, r.Account.Name.String)
+	}
+	shares := []string{}
+	for _, s := range r.Shares {
+		shares = append(shares, s.Employee.Username+"="+s.Share.RatString())
+	}
+	if got := strings.Join(shares, ","); got != "chris=1/4,alex=3/4" {
+		t.Errorf("shares %s", got)
+	}
+
+	// Memo regex in the first column, description regex in the second
+	tc, ok := c.Rules[1].Condition.(textCondition)
+	if !ok || tc.memo != nil || tc.desc == nil || tc.desc.String() != "Wasser" {
+		t.Errorf("condition %+v, want description regex Wasser", c.Rules[1].Condition)
+	}
+}
+
+func TestLoadCSVMonthlyConfigErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		file   string
+		config string
+		want   []string
+	}{
+		{"unknown vendor", "EWS.csv", "Strom,,Electricity,Expenses:Utilities\n",
+			[]string{"unable to find vendor 'EWS'"}},
+		{"vendor by alias", "Stadtwerke.csv", "Strom,,Electricity,Expenses:Utilities\n", nil},
+		{"too few columns", "SWM.csv", "Strom,,Electricity\n",
+			[]string{"line 1: expected at least 4 columns"}},
+		{"invalid regexes", "SWM.csv", "Strom(,Strom[,Electricity,Expenses:Utilities\n",
+			[]string{"line 1: error in regex 'Strom('", "line 1: error in regex 'Strom['"}},
+		{"unknown account", "SWM.csv", "Strom,,Electricity,Expenses:Power\n",
+			[]string{"line 1: unable to find account 'Expenses:Power'"}},
+		{"invalid shares", "SWM.csv", "Strom,,Electricity,Expenses:Utilities\n" +
+			"Wasser,,Water,Expenses:Utilities,chris,3/4,alex,1/2\n",
+			[]string{"line 2: shares add up to 5/4, more than 1"}},
+		{"all errors", "EWS.csv", "Strom,,Electricity,Expenses:Power\nWasser\n",
+			[]string{"unable to find vendor 'EWS'", "line 1: unable to find account 'Expenses:Power'",
+				"line 2: expected at least 4 columns"}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			book := openTestBook(t)
+			_, errs := loadCSVMonthlyConfig(book, writeConfig(t, tc.file, tc.config))
+			checkErrors(t, errs, tc.want...)
+		})
+	}
+}
+
+func TestParseShareValue(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+		err   string
+	}{
+		{"1/3", "1/3", ""},
+		{"0.25", "1/4", ""},
+		{"25%", "1/4", ""},
+		{" 12.5% ", "1/8", ""},
+		{"1", "1", ""},
+		{"100%", "1", ""},
+		{"0", "", "share '0' is not within (0, 1]"},
+		{"0%", "", "share '0%' is not within (0, 1]"},
+		{"-1/4", "", "share '-1/4' is not within (0, 1]"},
+		{"1.5", "", "share '1.5' is not within (0, 1]"},
+		{"150%", "", "share '150%' is not within (0, 1]"},
+		{"4/3", "", "share '4/3' is not within (0, 1]"},
+		{"half", "", "invalid share 'half'"},
+		{"", "", "invalid share ''"},
+		{"1/0", "", "invalid share '1/0'"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.value, func(t *testing.T) {
+			share, err := parseShareValue(tc.value)
+			if tc.err != "" {
+				if err == nil || err.Error() != tc.err {
+					t.Errorf("error %v, want %q", err, tc.err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+			if share.RatString() != tc.want {
+				t.Errorf("share %s, want %s", share.RatString(), tc.want)
+			}
+		})
+	}
+}
+
+func TestParseShares(t *testing.T) {
+	groups := map[string][]string{
+		"flat":  {"alex", "sam"},
+		"all":   {"chris", "alex", "sam"},
+		"team":  {"chris", "pat"},
+		"empty": {},
+	}
+
+	tests := []struct {
+		name  string
+		specs []shareSpec
+		want  string // username=share, in order
+		errs  []string
+	}{
+		{"none", nil, "", nil},
+		{"whole", []shareSpec{{"chris", "1"}}, "chris=1", nil},
+		{"less than 1", []shareSpec{{"chris", "1/2"}}, "chris=1/2", nil},
+		{"mixed notations", []shareSpec{{"chris", "25%"}, {"alex", "0.5"}, {"sam", "1/4"}},
+			"chris=1/4,alex=1/2,sam=1/4", nil},
+		{"thirds", []shareSpec{{"chris", "1/3"}, {"alex", "1/3"}, {"sam", "1/3"}},
+			"chris=1/3,alex=1/3,sam=1/3", nil},
+		{"rest", []shareSpec{{"chris", "1/4"}, {"alex", "rest"}}, "chris=1/4,alex=3/4", nil},
+		{"rest first", []shareSpec{{"alex", "rest"}, {"chris", "1/4"}}, "alex=3/4,chris=1/4", nil},
+		{"only rest", []shareSpec{{"alex", "rest"}}, "alex=1", nil},
+		{"group", []shareSpec{{"@flat", "1/2"}}, "alex=1/4,sam=1/4", nil},
+		{"group rest", []shareSpec{{"chris", "25%"}, {"@flat", "rest"}}, "chris=1/4,alex=3/8,sam=3/8", nil},
+		{"group of three", []shareSpec{{"@all", "1"}}, "chris=1/3,alex=1/3,sam=1/3", nil},
+		{"more than 1", []shareSpec{{"chris", "3/4"}, {"alex", "1/2"}}, "",
+			[]string{"shares add up to 5/4, more than 1"}},
+		{"two rests", []shareSpec{{"chris", "rest"}, {"alex", "rest"}}, "",
+			[]string{"only one remainder share may be given"}},
+		{"nothing left", []shareSpec{{"chris", "1"}, {"alex", "rest"}}, "",
+			[]string{"nothing left for remainder share"}},
+		{"unknown employee", []shareSpec{{"kim", "1/2"}}, "",
+			[]string{"unable to find employee 'kim'"}},
+		{"inactive employee", []shareSpec{{"pat", "1/2"}}, "",
+			[]string{"employee 'pat' is inactive"}},
+		{"inactive group member", []shareSpec{{"@team", "1/2"}}, "",
+			[]string{"employee 'pat' is inactive"}},
+		{"charged twice", []shareSpec{{"chris", "1/4"}, {"@all", "1/2"}}, "",
+			[]string{"employee 'chris' is given more than one share"}},
+		{"unknown group", []shareSpec{{"@office", "1/2"}}, "",
+			[]string{"unknown or empty group '@office'"}},
+		{"empty group", []shareSpec{{"@empty", "1/2"}}, "",
+			[]string{"unknown or empty group '@empty'"}},
+		{"invalid share", []shareSpec{{"chris", "2"}}, "",
+			[]string{"share '2' is not within (0, 1]"}},
+		{"all errors", []shareSpec{{"kim", "1/2"}, {"chris", "half"}, {"alex", "3/4"}, {"sam", "rest"}, {"@flat", "rest"}}, "",
+			[]string{"unable to find employee 'kim'", "invalid share 'half'",
+				"employee 'alex' is given more than one share", "employee 'sam' is given more than one share",
+				"only one remainder share may be given", "shares add up to 5/4, more than 1",
+				"nothing left for remainder share"}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			book := openTestBook(t)
+
+			shares, errs := parseShares(book, groups, tc.specs)
+			checkErrors(t, errs, tc.errs...)
+			if len(errs) > 0 {
+				if shares != nil {
+					t.Errorf("shares %v returned together with errors", shares)
+				}
+				return
+			}
+
+			got := []string{}
+			for _, s := range shares {
+				got = append(got, s.Employee.Username+"="+s.Share.RatString())
+			}
+			if strings.Join(got, ",") != tc.want {
+				t.Errorf("shares %s, want %s", strings.Join(got, ","), tc.want)
+			}
+		})
+	}
+}
@@ -17,8 +17,34 @@ var (
 	delimiter          string
 	generateConfigFile string
 	payableAccount     string
+	receivableAccount  string
 	startDate          string
 	endDate            string
+	dateStrategy       string
+	groupBy            string
+	postVouchers       bool
+	employeeAccount    string
+	appendDocuments    bool
+	createVendors      bool
+	forceLock          bool
+
+	undoRunId            string
+	undoRunDate          string
+	undoConfig           string
+	undoRollbackCounters bool
+	undoDryRun           bool
+
+	vendorsRemoveAlias bool
+
+	employeesAll         bool
+	employeeUsername     string
+	employeeName         string
+	employeeEmail        string
+	employeePhone        string
+	employeeCCardAccount string
+	employeeRate         string
+	employeeActive       bool
+	employeeInactive     bool
 
 	rootCmd = &cobra.Command{
 		Use:   "matchmaker FILE MATCHFILE",
@@ -153,11 +179,62 @@ func init() {
 	monthlyCmd.Flags().StringVarP(&generateConfigFile, "generate", "g", "", "generate bills using specified CSV config file")
 	monthlyCmd.Flags().StringVarP(&payableAccount, "payable-account", "a", "Liabilities:Accounts Payable",
 		"account used to search for matches when generating bills")
+	monthlyCmd.Flags().StringVar(&receivableAccount, "receivable-account", "Assets:Accounts Receivable",
+		"account generated customer invoices are posted to")
 	monthlyCmd.Flags().StringVarP(&startDate, "start-date", "s", "", "start date of transactions to include (YYYY-MM-DD, optional)")
 	monthlyCmd.Flags().StringVarP(&endDate, "end-date", "e", "", "end date of transactions to include (YYYY-MM-DD, optional)")
+	monthlyCmd.Flags().StringVarP(&dateStrategy, "date-strategy", "t", "today", "date used for opening and posting"+
+		" generated documents: 'earliest' or 'latest' matched payment, 'period-end' or 'today'")
+	monthlyCmd.Flags().StringVarP(&groupBy, "group-by", "b", "none", "generate separate bills per billing"+
+		" period: 'month', 'week', 'transaction' or 'none'")
+	monthlyCmd.Flags().BoolVarP(&postVouchers, "post-vouchers", "p", false, "post generated expense vouchers"+
+		" (left unposted for manual editing by default)")
+	monthlyCmd.Flags().StringVar(&employeeAccount, "employee-account", "", "liability account expense vouchers"+
+		" are posted to (defaults to the payable account)")
+	monthlyCmd.Flags().BoolVar(&appendDocuments, "append", false, "add entries to an open (unposted) bill or"+
+		" voucher of the same billing period instead of creating a new one")
+	monthlyCmd.Flags().BoolVar(&createVendors, "create-vendors", false, "create vendors named by config files"+
+		" that are not in the book (after confirmation)")
+
+	undoCmd.Flags().StringVarP(&undoRunId, "run", "r", "", "undo documents generated by the run with this ID")
+	undoCmd.Flags().StringVarP(&undoRunDate, "date", "d", "", "undo documents generated on this date (YYYY-MM-DD)")
+	undoCmd.Flags().StringVarP(&undoConfig, "config", "c", "", "undo bills and invoices generated from this config file")
+	undoCmd.Flags().BoolVar(&undoRollbackCounters, "rollback-counters", false, "roll bill, invoice and voucher counters"+
+		" back if the undone documents were the last ones issued")
+	undoCmd.Flags().BoolVarP(&undoDryRun, "dry-run", "n", false, "only list the documents that would be undone")
+
+	vendorsAliasCmd.Flags().BoolVarP(&vendorsRemoveAlias, "remove", "r", false, "remove the aliases instead of"+
+		" adding them")
+	vendorsCmd.AddCommand(vendorsListCmd)
+	vendorsCmd.AddCommand(vendorsAliasCmd)
+
+	employeesListCmd.Flags().BoolVar(&employeesAll, "all", false, "include inactive employees")
+	for _, c := range []*cobra.Command{employeesAddCmd, employeesEditCmd} {
+		c.Flags().StringVar(&employeeName, "name", "", "full name of the employee")
+		c.Flags().StringVar(&employeeEmail, "email", "", "email address of the employee")
+		c.Flags().StringVar(&employeePhone, "phone", "", "phone number of the employee")
+		c.Flags().StringVar(&employeeCCardAccount, "ccard-account", "", "credit card account expenses paid"+
+			" by card are booked against ('' for none)")
+		c.Flags().StringVar(&employeeRate, "rate", "", "hourly rate of the employee")
+	}
+	employeesEditCmd.Flags().StringVar(&employeeUsername, "username", "", "new username of the employee")
+	employeesEditCmd.Flags().BoolVar(&employeeActive, "activate", false, "mark the employee as active")
+	employeesEditCmd.Flags().BoolVar(&employeeInactive, "deactivate", false, "mark the employee as inactive"+
+		" (no new shares can be given to inactive employees)")
+	employeesCmd.AddCommand(employeesListCmd)
+	employeesCmd.AddCommand(employeesAddCmd)
+	employeesCmd.AddCommand(employeesEditCmd)
+
+	for _, c := range []*cobra.Command{monthlyCmd, undoCmd, vendorsAliasCmd, employeesAddCmd, employeesEditCmd} {
+		c.Flags().BoolVar(&forceLock, "force", false, "write to the book even if it is locked by GnuCash"+
+			" or another process")
+	}
 
 	rootCmd.AddCommand(testCmd)
 	rootCmd.AddCommand(monthlyCmd)
+	rootCmd.AddCommand(undoCmd)
+	rootCmd.AddCommand(vendorsCmd)
+	rootCmd.AddCommand(employeesCmd)
 }
 
 func initConfig() {
@@ -23,7 +23,10 @@ var (
 			}
 			defer book.Close()
 
-			curr := book.GetDefaultCurrency()
+			curr, err := book.GetDefaultCurrency()
+			if err != nil {
+				log.Fatal(err)
+			}
 
 			swm := book.GetVendorByName("SWM")
 			if swm == nil {
@@ -46,10 +49,15 @@ var (
 
 			return
 
+			counter, err := book.GetBillCounter()
+			if err != nil {
+				log.Fatal(err)
+			}
+
 			invoice := &gnucash.Invoice{
 				IsCreditNote: true,
 				DbInvoice: gnucash.DbInvoice{
-					Id:             strconv.FormatInt(book.GetBillCounter()+1, 10),
+					Id:             strconv.FormatInt(counter+1, 10),
 					Active:         1,
 					DateOpened:     sql.NullString{gnucash.GetCurrentTimeString(), true},
 					Currency:       curr.Guid,
@@ -60,7 +68,9 @@ var (
 				},
 			}
 
-			book.AddInvoice(invoice)
+			if err := book.AddInvoice(invoice); err != nil {
+				log.Fatal(err)
+			}
 
 			entry := &gnucash.Entry{
 				DbEntry: gnucash.DbEntry{
@@ -75,7 +85,9 @@ var (
 				},
 			}
 
-			invoice.AddEntry(entry)
+			if err := invoice.AddEntry(entry); err != nil {
+				log.Fatal(err)
+			}
 		},
 	}
 )
@@ -0,0 +1,26 @@
+-- Book with the accounts, vendor, customer and employees monthly configs
+-- refer to, loaded on top of ../gnucash/testdata/schema.sql
+INSERT INTO books VALUES('book0000000000000000000000000001','root0000000000000000000000000001','tmpl0000000000000000000000000001');
+INSERT INTO commodities VALUES('eur00000000000000000000000000001','CURRENCY','EUR','Euro','978',100,1,'currency',NULL);
+INSERT INTO accounts VALUES('root0000000000000000000000000001','Root Account','ROOT','eur00000000000000000000000000001',100,0,NULL,'','',0,0);
+INSERT INTO accounts VALUES('tmpl0000000000000000000000000001','Template Root','ROOT',NULL,0,0,NULL,'','',0,0);
+INSERT INTO accounts VALUES('asst0000000000000000000000000001','Assets','ASSET','eur00000000000000000000000000001',100,0,'root0000000000000000000000000001','','',0,1);
+INSERT INTO accounts VALUES('bank0000000000000000000000000001','Bank','BANK','eur00000000000000000000000000001',100,0,'asst0000000000000000000000000001','','',0,1);
+INSERT INTO accounts VALUES('chk00000000000000000000000000001','Checking','BANK','eur00000000000000000000000000001',100,0,'bank0000000000000000000000000001','','',0,0);
+INSERT INTO accounts VALUES('sav00000000000000000000000000001','Savings','BANK','eur00000000000000000000000000001',100,0,'bank0000000000000000000000000001','','',0,0);
+INSERT INTO accounts VALUES('ar000000000000000000000000000001','Accounts Receivable','RECEIVABLE','eur00000000000000000000000000001',100,0,'asst0000000000000000000000000001','','',0,0);
+INSERT INTO accounts VALUES('liab0000000000000000000000000001','Liabilities','LIABILITY','eur00000000000000000000000000001',100,0,'root0000000000000000000000000001','','',0,1);
+INSERT INTO accounts VALUES('ap000000000000000000000000000001','Accounts Payable','PAYABLE','eur00000000000000000000000000001',100,0,'liab0000000000000000000000000001','','',0,0);
+INSERT INTO accounts VALUES('cc000000000000000000000000000001','Credit Card','CREDIT','eur00000000000000000000000000001',100,0,'liab0000000000000000000000000001','','',0,0);
+INSERT INTO accounts VALUES('expn0000000000000000000000000001','Expenses','EXPENSE','eur00000000000000000000000000001',100,0,'root0000000000000000000000000001','','',0,1);
+INSERT INTO accounts VALUES('util0000000000000000000000000001','Utilities','EXPENSE','eur00000000000000000000000000001',100,0,'expn0000000000000000000000000001','','',0,0);
+INSERT INTO accounts VALUES('inc00000000000000000000000000001','Income','INCOME','eur00000000000000000000000000001',100,0,'root0000000000000000000000000001','','',0,1);
+INSERT INTO accounts VALUES('rbc00000000000000000000000000001','Rebilled Costs','INCOME','eur00000000000000000000000000001',100,0,'inc00000000000000000000000000001','','',0,0);
+INSERT INTO vendors VALUES('vswm0000000000000000000000000001','SWM','000001','','eur00000000000000000000000000001',1,0,'SWM','','','','','','','',NULL,'1',NULL);
+INSERT INTO slots(obj_guid,name,slot_type,guid_val) VALUES('vswm0000000000000000000000000001','aliases',9,'alss0000000000000000000000000001');
+INSERT INTO slots(obj_guid,name,slot_type,string_val) VALUES('alss0000000000000000000000000001','aliases/Stadtwerke',4,'Stadtwerke');
+INSERT INTO customers VALUES('cacm0000000000000000000000000001','ACME','000001','',1,0,1,0,1,'eur00000000000000000000000000001',0,'ACME','','','','','','','','','','','','','','','',NULL,NULL,NULL);
+INSERT INTO employees VALUES('echr0000000000000000000000000001','chris','000001','','',1,'eur00000000000000000000000000001',NULL,800,100,0,1,'Chris','','','','','','','');
+INSERT INTO employees VALUES('ealx0000000000000000000000000001','alex','000002','','',1,'eur00000000000000000000000000001','cc000000000000000000000000000001',800,100,0,1,'Alex','','','','','','','');
+INSERT INTO employees VALUES('esam0000000000000000000000000001','sam','000003','','',1,'eur00000000000000000000000000001',NULL,800,100,0,1,'Sam','','','','','','','');
+INSERT INTO employees VALUES('epat0000000000000000000000000001','pat','000004','','',0,'eur00000000000000000000000000001',NULL,800,100,0,1,'Pat','','','','','','','');
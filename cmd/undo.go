@@ -0,0 +1,450 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"bvorhofer.com/matchmaker/gnucash"
+	"github.com/spf13/cobra"
+)
+
+var (
+	undoCmd = &cobra.Command{
+		Use:   "undo FILE",
+		Short: "Undo bills, invoices and vouchers generated by monthly",
+		Long: `Undo bills, invoices and vouchers generated by monthly. Generated bills and customer
+invoices are selected by run ID, run date and/or config file. Their payments are unassigned,
+they are unposted and deleted together with the voucher entries that were split off from them.
+Entries a run added to an open document (--append) are undone by selecting that run: they are
+removed together with their payment assignments, the document itself is kept unposted.`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			if undoRunId == "" && undoRunDate == "" && undoConfig == "" {
+				fatal("At least one of --run, --date or --config has to be given")
+			}
+
+			book, err := gnucash.OpenBookFromSQLite(args[0])
+			if err != nil {
+				fatal(err)
+			}
+			defer book.Close()
+			if !undoDryRun {
+				lockBook(book)
+			}
+
+			// Find generated bills and invoices matching the given filters, and
+			// documents runs matching them added entries to
+			type billUndo struct {
+				bill *gnucash.Invoice
+				runs map[string]bool // runs that added the entries to undo, nil for all
+			}
+			bills := []billUndo{}
+			billRuns := make(map[string]map[string]bool)
+			for _, i := range book.GetInvoices() {
+				if i.GetEndOwnerType() != gnucash.OwnerTypeVendor && i.GetEndOwnerType() != gnucash.OwnerTypeCustomer {
+					continue
+				}
+
+				config := getGeneratedString(book, i.Guid, generatedConfigSlot)
+				if undoConfig != "" && config != undoConfig {
+					continue
+				}
+
+				runId := getGeneratedString(book, i.Guid, generatedRunIdSlot)
+				if runId != "" && undoMatchesRun(runId, getGeneratedString(book, i.Guid, generatedRunDateSlot)) {
+					bills = append(bills, billUndo{bill: i})
+					billRuns[i.Guid] = nil
+					continue
+				}
+
+				runs := make(map[string]bool)
+				for _, run := range getGeneratedFrame(book, i.Guid, generatedAddedSlot) {
+					if undoMatchesRun(run, runDate(run)) {
+						runs[run] = true
+					}
+				}
+				if len(runs) > 0 {
+					bills = append(bills, billUndo{bill: i, runs: runs})
+					billRuns[i.Guid] = runs
+				}
+			}
+
+			// Find voucher entries that were split off from these bills (by the
+			// selected runs)
+			type voucherUndo struct {
+				voucher *gnucash.Invoice
+				entries []*gnucash.Entry
+			}
+			vouchers := []voucherUndo{}
+			for _, i := range book.GetInvoices() {
+				if i.GetOwnerType() != gnucash.OwnerTypeEmployee {
+					continue
+				}
+
+				sources := getGeneratedFrame(book, i.Guid, generatedEntriesSlot)
+				added := getGeneratedFrame(book, i.Guid, generatedAddedSlot)
+				vu := voucherUndo{voucher: i}
+				for _, e := range i.Entries {
+					runs, ok := billRuns[sources[e.Guid]]
+					if !ok {
+						continue
+					}
+
+					if runs != nil {
+						run, ok := added[e.Guid]
+						if !ok {
+							run = getGeneratedString(book, i.Guid, generatedRunIdSlot)
+						}
+						if !runs[run] {
+							continue
+						}
+					}
+
+					vu.entries = append(vu.entries, e)
+				}
+
+				if len(vu.entries) > 0 {
+					vouchers = append(vouchers, vu)
+				}
+			}
+
+			if len(bills) == 0 {
+				fmt.Println("No generated bills or invoices found")
+				return
+			}
+
+			undone := make(map[gnucash.OwnerType][]string)
+
+			for _, bu := range bills {
+				bill := bu.bill
+				run := getGeneratedString(book, bill.Guid, generatedRunIdSlot)
+				if bu.runs != nil {
+					run = strings.Join(sortedKeys(bu.runs), ", ")
+				}
+				config := getGeneratedString(book, bill.Guid, generatedConfigSlot)
+				if bill.GetEndOwnerType() == gnucash.OwnerTypeCustomer {
+					fmt.Printf("INVOICE %s for customer %s (run %s, %s)\n", bill.Id, bill.GetOwnerName(), run, config)
+				} else {
+					fmt.Printf("BILL %s from vendor %s (run %s, %s)\n", bill.Id, bill.GetOwnerName(), run, config)
+				}
+
+				if undoDryRun {
+					continue
+				}
+
+				splits := generatedSplits(book, bill, bu.runs)
+				unassignPayments(book, bill, splits)
+
+				if bill.DatePosted.Valid {
+					if err := bill.Unpost(); err != nil {
+						fatal(err)
+					}
+				}
+
+				// Documents runs only added entries to are kept (unposted)
+				if bu.runs != nil {
+					added := getGeneratedFrame(book, bill.Guid, generatedAddedSlot)
+					entries := []*gnucash.Entry{}
+					for _, e := range bill.Entries {
+						if bu.runs[added[e.Guid]] {
+							entries = append(entries, e)
+						}
+					}
+
+					for _, e := range entries {
+						if err := e.Delete(); err != nil {
+							fatal(err)
+						}
+						if err := book.DeleteSlot(bill.Guid, generatedAddedSlot+"/"+e.Guid); err != nil {
+							fatal(err)
+						}
+					}
+					forgetSplits(book, bill, splits)
+
+					fmt.Printf("  removed %d added entries, document is left unposted\n", len(entries))
+					continue
+				}
+
+				if added := getGeneratedFrame(book, bill.Guid, generatedAddedSlot); len(added) > 0 {
+					log.Printf("WARNING: %s also has %d entries added by later runs, they are removed as well\n",
+						bill.Id, len(added))
+				}
+				if err := bill.Delete(); err != nil {
+					fatal(err)
+				}
+
+				undone[bill.GetEndOwnerType()] = append(undone[bill.GetEndOwnerType()], bill.Id)
+			}
+
+			for _, vu := range vouchers {
+				// Vouchers monthly only added entries to are kept
+				all := len(vu.entries) == len(vu.voucher.Entries) &&
+					getGeneratedString(book, vu.voucher.Guid, generatedRunIdSlot) != ""
+				if all {
+					fmt.Printf("VOUCHER %s for employee %s\n", vu.voucher.Id,
+						vu.voucher.GetEmployee().Username)
+				} else {
+					fmt.Printf("VOUCHER %s for employee %s (%d of %d entries)\n", vu.voucher.Id,
+						vu.voucher.GetEmployee().Username, len(vu.entries), len(vu.voucher.Entries))
+				}
+
+				if undoDryRun {
+					continue
+				}
+
+				if vu.voucher.DatePosted.Valid {
+					if err := vu.voucher.Unpost(); err != nil {
+						fatal(err)
+					}
+					if !all {
+						log.Printf("WARNING: Voucher %s has been unposted, please post it again\n",
+							vu.voucher.Id)
+					}
+				}
+
+				if all {
+					if err := vu.voucher.Delete(); err != nil {
+						fatal(err)
+					}
+					undone[gnucash.OwnerTypeEmployee] = append(undone[gnucash.OwnerTypeEmployee], vu.voucher.Id)
+				} else {
+					for _, e := range vu.entries {
+						if err := e.Delete(); err != nil {
+							fatal(err)
+						}
+						for _, frame := range []string{generatedEntriesSlot, generatedAddedSlot} {
+							if err := book.DeleteSlot(vu.voucher.Guid, frame+"/"+e.Guid); err != nil {
+								fatal(err)
+							}
+						}
+					}
+				}
+			}
+
+			if undoRollbackCounters && !undoDryRun {
+				rollbackCounter(book.GetBillCounter, book.SetBillCounter,
+					undone[gnucash.OwnerTypeVendor], "Bill")
+				rollbackCounter(book.GetInvoiceCounter, book.SetInvoiceCounter,
+					undone[gnucash.OwnerTypeCustomer], "Invoice")
+				rollbackCounter(book.GetExpenseVoucherCounter, book.SetExpenseVoucherCounter,
+					undone[gnucash.OwnerTypeEmployee], "Expense voucher")
+			}
+		},
+	}
+)
+
+// Returns true if the run with the given ID and date matches the run filters
+func undoMatchesRun(runId string, runDate string) bool {
+	return (undoRunId == "" || runId == undoRunId) &&
+		(undoRunDate == "" || strings.HasPrefix(runDate, undoRunDate))
+}
+
+// Returns the date of a run (as recorded in gncx/run-date) from its ID, "" if
+// the ID isn't a timestamp
+func runDate(runId string) string {
+	t, err := time.ParseInLocation("20060102150405", runId, time.Local)
+	if err != nil {
+		return ""
+	}
+
+	return t.Format("2006-01-02 15:04:05")
+}
+
+// Returns the GUIDs of the source splits a generated document billed in the
+// given runs (in all runs if runs is nil). Splits recorded without run belong
+// to the run that generated the document.
+func generatedSplits(book *gnucash.Book, bill *gnucash.Invoice, runs map[string]bool) []string {
+	splitRuns := getGeneratedFrame(book, bill.Guid, generatedSplitRunsSlot)
+	docRun := getGeneratedString(book, bill.Guid, generatedRunIdSlot)
+
+	splits := []string{}
+	for splitGuid := range getGeneratedFrame(book, bill.Guid, generatedSplitsSlot) {
+		run, ok := splitRuns[splitGuid]
+		if !ok {
+			run = docRun
+		}
+		if runs == nil || runs[run] {
+			splits = append(splits, splitGuid)
+		}
+	}
+	sort.Strings(splits)
+
+	return splits
+}
+
+// Restores the payment splits in the txns of the given source splits of a
+// generated bill to the state they were in before the bill was generated
+func unassignPayments(book *gnucash.Book, bill *gnucash.Invoice, splits []string) {
+	actions := getGeneratedFrame(book, bill.Guid, generatedActionsSlot)
+	lots := getGeneratedFrame(book, bill.Guid, generatedLotsSlot)
+	accounts := getGeneratedFrame(book, bill.Guid, generatedAccountsSlot)
+	txnTypes := getGeneratedFrame(book, bill.Guid, generatedTxnTypesSlot)
+
+	// Nothing to restore for documents without payments (e.g. customer invoices)
+	if len(actions) == 0 {
+		return
+	}
+
+	txns := make(map[string]bool)
+	for _, splitGuid := range splits {
+		if s := book.GetSplitByGUID(splitGuid); s != nil {
+			txns[s.TxGuid] = true
+		}
+	}
+
+	// Merge the excess of overpayments back into the original payment splits
+	for excessGuid, splitGuid := range getGeneratedFrame(book, bill.Guid, generatedExcessSlot) {
+		excess := book.GetSplitByGUID(excessGuid)
+		s := book.GetSplitByGUID(splitGuid)
+		if excess == nil || s == nil {
+			log.Printf("WARNING: Overpayment split %s of bill %s not found, skipping...\n",
+				excessGuid, bill.Id)
+			continue
+		}
+
+		if !txns[s.TxGuid] {
+			continue
+		}
+
+		if err := s.Join(excess); err != nil {
+			fatal(err)
+		}
+	}
+
+	for _, splitGuid := range splits {
+		s := book.GetSplitByGUID(splitGuid)
+		if s == nil {
+			log.Printf("WARNING: Payment split %s of bill %s not found, skipping...\n",
+				splitGuid, bill.Id)
+			continue
+		}
+
+		for _, ts := range s.Transaction.Splits {
+			action, ok := actions[ts.Guid]
+			if !ok {
+				continue
+			}
+
+			ts.Action = action
+			if err := ts.Write(); err != nil {
+				fatal(err)
+			}
+
+			// Also removes the pre-payment lot of payments that were not due
+			if err := ts.SetLot(book.GetLotByGUID(lots[ts.Guid])); err != nil {
+				fatal(err)
+			}
+
+			// Move split back if it was moved to the payable account
+			if accGuid, ok := accounts[ts.Guid]; ok && accGuid != ts.AccountGuid {
+				acc := book.GetAccountByGUID(accGuid)
+				if acc == nil {
+					log.Printf("WARNING: Original account %s of split %s not found\n",
+						accGuid, ts.Guid)
+					continue
+				}
+
+				if err := ts.SetAccount(acc); err != nil {
+					fatal(err)
+				}
+			}
+		}
+
+		txnType, ok := txnTypes[s.Transaction.Guid]
+		if !ok {
+			continue
+		}
+
+		if txnType == "" {
+			if slot := book.GetSlot(s.Transaction.Guid, transactionTypeSlotName); slot != nil {
+				if err := book.RemoveSlot(slot); err != nil {
+					fatal(err)
+				}
+			}
+		} else {
+			setSlotString(book, s.Transaction.Guid, transactionTypeSlotName, txnType)
+		}
+	}
+}
+
+// Removes the records of the given source splits (and of the payments in
+// their txns) from a generated document
+func forgetSplits(book *gnucash.Book, bill *gnucash.Invoice, splits []string) {
+	remove := func(path string) {
+		if err := book.DeleteSlot(bill.Guid, path); err != nil {
+			fatal(err)
+		}
+	}
+
+	excess := getGeneratedFrame(book, bill.Guid, generatedExcessSlot)
+	for _, splitGuid := range splits {
+		remove(generatedSplitsSlot + "/" + splitGuid)
+		remove(generatedSplitRunsSlot + "/" + splitGuid)
+
+		s := book.GetSplitByGUID(splitGuid)
+		if s == nil {
+			continue
+		}
+
+		remove(generatedTxnTypesSlot + "/" + s.TxGuid)
+		for _, ts := range s.Transaction.Splits {
+			remove(generatedActionsSlot + "/" + ts.Guid)
+			remove(generatedLotsSlot + "/" + ts.Guid)
+			remove(generatedAccountsSlot + "/" + ts.Guid)
+		}
+		for excessGuid, paymentGuid := range excess {
+			if p := book.GetSplitByGUID(paymentGuid); p == nil || p.TxGuid == s.TxGuid {
+				remove(generatedExcessSlot + "/" + excessGuid)
+			}
+		}
+	}
+}
+
+// Returns the keys of a set in ascending order
+func sortedKeys(set map[string]bool) []string {
+	keys := []string{}
+	for k := range set {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+// Decrements a counter as long as its last issued number (the counter itself,
+// as GnuCash increments it before formatting an ID) belongs to one of the
+// undone documents
+func rollbackCounter(get func() (int64, error), set func(int64) error, ids []string, name string) {
+	nums := []int64{}
+	for _, id := range ids {
+		n, err := strconv.ParseInt(id, 10, 64)
+		if err == nil {
+			nums = append(nums, n)
+		}
+	}
+	sort.Slice(nums, func(i, j int) bool { return nums[i] > nums[j] })
+
+	current, err := get()
+	if err != nil {
+		fatal(err)
+	}
+
+	counter := current
+	for _, n := range nums {
+		if counter != n {
+			break
+		}
+		counter--
+	}
+
+	if counter != current {
+		if err := set(counter); err != nil {
+			fatal(err)
+		}
+		fmt.Printf("%s counter rolled back to %d\n", name, counter)
+	}
+}
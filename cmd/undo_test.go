@@ -0,0 +1,36 @@
+package cmd
+
+import "testing"
+
+func TestRollbackCounter(t *testing.T) {
+	tests := []struct {
+		name    string
+		counter int64
+		ids     []string
+		want    int64
+	}{
+		{"last two", 7, []string{"00006", "00007"}, 5},
+		{"last two unordered", 7, []string{"00007", "00006"}, 5},
+		{"last only", 7, []string{"00007"}, 6},
+		{"gap", 7, []string{"00005", "00007"}, 6},
+		{"not the last", 7, []string{"00006"}, 7},
+		{"all", 2, []string{"00001", "00002"}, 0},
+		{"non-numeric", 7, []string{"INV-7"}, 7},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			counter := tc.counter
+			get := func() (int64, error) { return counter, nil }
+			set := func(n int64) error {
+				counter = n
+				return nil
+			}
+
+			rollbackCounter(get, set, tc.ids, "Bill")
+			if counter != tc.want {
+				t.Errorf("counter = %d, want %d", counter, tc.want)
+			}
+		})
+	}
+}
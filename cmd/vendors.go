@@ -0,0 +1,85 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"bvorhofer.com/matchmaker/gnucash"
+	"github.com/spf13/cobra"
+)
+
+var (
+	vendorsCmd = &cobra.Command{
+		Use:   "vendors",
+		Short: "List vendors and maintain their aliases",
+	}
+
+	vendorsListCmd = &cobra.Command{
+		Use:   "list FILE",
+		Short: "List all vendors with their aliases",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			book, err := gnucash.OpenBookFromSQLite(args[0],
+				gnucash.LoadTables(gnucash.TableSlots|gnucash.TableVendors))
+			if err != nil {
+				fatal(err)
+			}
+			defer book.Close()
+
+			for _, v := range book.GetVendors() {
+				line := fmt.Sprintf("%s %s", v.Id, v.Name)
+				if aliases := v.GetAliases(); len(aliases) > 0 {
+					line += " (aliases: " + strings.Join(aliases, ", ") + ")"
+				}
+				if v.Active == 0 {
+					line += " [inactive]"
+				}
+
+				fmt.Println(line)
+			}
+		},
+	}
+
+	vendorsAliasCmd = &cobra.Command{
+		Use:   "alias FILE VENDOR ALIAS...",
+		Short: "Add aliases to a vendor",
+		Long: `Add aliases to a vendor (given by name or ID). Config files of monthly may name a vendor
+by one of its aliases instead of its name.`,
+		Args: cobra.MinimumNArgs(3),
+		Run: func(cmd *cobra.Command, args []string) {
+			book, err := gnucash.OpenBookFromSQLite(args[0],
+				gnucash.LoadTables(gnucash.TableSlots|gnucash.TableVendors))
+			if err != nil {
+				fatal(err)
+			}
+			defer book.Close()
+			lockBook(book)
+
+			v := book.GetVendorByName(args[1])
+			if v == nil {
+				v = book.GetVendorByID(args[1])
+			}
+			if v == nil {
+				fatalf("Unable to find vendor '%s'\n", args[1])
+			}
+
+			for _, alias := range args[2:] {
+				if vendorsRemoveAlias {
+					if err := v.RemoveAlias(alias); err != nil {
+						fatal(err)
+					}
+					continue
+				}
+
+				if o := findVendor(book, alias); o != nil && o != v {
+					fatalf("'%s' already names vendor %s\n", alias, o.Name)
+				}
+				if err := v.AddAlias(alias); err != nil {
+					fatal(err)
+				}
+			}
+
+			fmt.Printf("%s %s (aliases: %s)\n", v.Id, v.Name, strings.Join(v.GetAliases(), ", "))
+		},
+	}
+)
@@ -10,7 +10,9 @@ type Account struct {
 	DbAccount
 	Parent   *Account
 	Children []*Account
-	Splits   []*Split
+	Splits   []*Split // all splits unless the book is loaded lazily, see GetSplits
+
+	splitsLoaded bool
 }
 
 type DbAccount struct {
@@ -27,6 +29,17 @@ type DbAccount struct {
 	Placeholder   int
 }
 
+// Returns true if the account normally has a debit balance (assets and
+// expenses), false for credit-normal accounts (liabilities, equity and income)
+func (a *Account) IsDebitNormal() bool {
+	switch a.Type.String {
+	case "ASSET", "BANK", "CASH", "STOCK", "MUTUAL", "RECEIVABLE", "EXPENSE":
+		return true
+	}
+
+	return false
+}
+
 func (a *Account) GetAccountByPath(path string) *Account {
 	splt := strings.Split(path, ":")
 	for _, child := range a.Children {
@@ -42,12 +55,72 @@ func (a *Account) GetAccountByPath(path string) *Account {
 	return nil
 }
 
-func (a *Account) AddLot(l *Lot) {
+func (a *Account) AddLot(l *Lot) error {
+	if err := a.book.checkComplete("adding a lot"); err != nil {
+		return err
+	}
+
 	if l.Guid == "" {
 		l.Guid = NewGuid()
 	}
 
 	l.book = a.book
+	if err := l.write(); err != nil {
+		return err
+	}
 	a.book.lots = append(a.book.lots, l)
-	l.write()
+	a.book.lotsByGuid[l.Guid] = l
+
+	return nil
+}
+
+// Returns the splits of the account, loading them (with their transactions)
+// first if the book is loaded lazily
+func (a *Account) GetSplits() ([]*Split, error) {
+	if a.splitsLoaded {
+		return a.Splits, nil
+	}
+
+	// Start with the splits loaded along with the transactions of other
+	// accounts, loadTransactions skips them
+	a.Splits = nil
+	for _, s := range a.book.splits {
+		if s.Account == a {
+			a.Splits = append(a.Splits, s)
+		}
+	}
+	a.splitsLoaded = true
+
+	opts := a.book.options
+	if opts.tables&TableTransactions == 0 || (a.book.subtree != nil && !a.book.subtree[a.Guid]) {
+		return a.Splits, nil
+	}
+
+	cond, args := opts.transactionCondition([]string{a.Guid})
+	if err := a.book.loadTransactions(cond, args...); err != nil {
+		return nil, err
+	}
+
+	return a.Splits, nil
+}
+
+// Returns all accounts below this account (children, grandchildren, etc.)
+func (a *Account) GetDescendants() []*Account {
+	accs := []*Account{}
+	for _, c := range a.Children {
+		accs = append(accs, c)
+		accs = append(accs, c.GetDescendants()...)
+	}
+
+	return accs
+}
+
+// Returns the full name of the account (e.g. "Liabilities:Credit Card"), the
+// inverse of Book.GetAccountByPath
+func (a *Account) GetPath() string {
+	if a.Parent == nil || a.Parent.Parent == nil {
+		return a.Name.String
+	}
+
+	return a.Parent.GetPath() + ":" + a.Name.String
 }
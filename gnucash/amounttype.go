@@ -0,0 +1,34 @@
+package gnucash
+
+// Type of tax table amounts and entry discounts
+type AmountType int
+
+const (
+	AmountTypeValue AmountType = iota + 1
+	AmountTypePercent
+)
+
+// Returns the name GnuCash uses for the amount type (e.g. in i_disc_type)
+func (t AmountType) String() string {
+	switch t {
+	case AmountTypeValue:
+		return "VALUE"
+	case AmountTypePercent:
+		return "PERCENT"
+	}
+
+	return ""
+}
+
+// When a discount is applied relative to taxes
+type DiscountHow string
+
+const (
+	// Discount is applied before taxes, which are computed on the discounted
+	// value
+	DiscountHowPretax DiscountHow = "PRETAX"
+	// Discount and taxes are both computed on the undiscounted value
+	DiscountHowSametime DiscountHow = "SAMETIME"
+	// Discount is computed on the value including taxes
+	DiscountHowPosttax DiscountHow = "POSTTAX"
+)
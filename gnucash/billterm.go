@@ -0,0 +1,119 @@
+package gnucash
+
+import (
+	"database/sql"
+	"math/big"
+	"time"
+)
+
+type BillTermType string
+
+const (
+	// Due a number of days after the post date
+	BillTermTypeDays BillTermType = "GNC_TERM_TYPE_DAYS"
+	// Due on a day of the next month (or the month after if posted after the
+	// cutoff day)
+	BillTermTypeProximo BillTermType = "GNC_TERM_TYPE_PROXIMO"
+)
+
+type BillTerm struct {
+	book *Book
+	DbBillTerm
+
+	indexed indexedKeys // keys of the book's indexes, see index
+}
+
+type DbBillTerm struct {
+	Guid          string
+	Name          string
+	Description   string
+	Refcount      int64
+	Invisible     int
+	Parent        sql.NullString
+	Type          string
+	DueDays       sql.NullInt32 `db:"duedays"`
+	DiscountDays  sql.NullInt32 `db:"discountdays"`
+	DiscountNum   sql.NullInt64 `db:"discount_num"`
+	DiscountDenom sql.NullInt64 `db:"discount_denom"`
+	Cutoff        sql.NullInt32
+}
+
+func (t *BillTerm) write() error {
+	query := `INSERT OR REPLACE INTO "billterms" ("guid", "name", "description",
+			"refcount", "invisible", "parent", "type", "duedays", "discountdays",
+			"discount_num", "discount_denom", "cutoff")
+		VALUES (:guid, :name, :description, :refcount, :invisible, :parent,
+			:type, :duedays, :discountdays, :discount_num, :discount_denom,
+			:cutoff)`
+
+	_, err := t.book.DB.NamedExec(query, t.DbBillTerm)
+	if err != nil {
+		return dbError(err, "writing billing terms %s", t.Name)
+	}
+
+	t.index()
+	return nil
+}
+
+// Updates the book's index by name
+func (t *BillTerm) index() {
+	t.book.billTermsByName.update(t.Guid, t.indexed.ok, t.indexed.name, t.Name)
+	t.indexed = indexedKeys{ok: true, name: t.Name}
+}
+
+// Returns the discount (in percent) granted for payment until the discount
+// date
+func (t *BillTerm) GetDiscount() *big.Rat {
+	if !t.DiscountNum.Valid || !t.DiscountDenom.Valid || t.DiscountDenom.Int64 == 0 {
+		return new(big.Rat)
+	}
+
+	return big.NewRat(t.DiscountNum.Int64, t.DiscountDenom.Int64)
+}
+
+// Returns the due date of an invoice posted on postDate
+func (t *BillTerm) ComputeDueDate(postDate time.Time) (time.Time, error) {
+	return t.computeDate(postDate, int(t.DueDays.Int32))
+}
+
+// Returns the last day a discount is granted for an invoice posted on postDate
+func (t *BillTerm) ComputeDiscountDate(postDate time.Time) (time.Time, error) {
+	return t.computeDate(postDate, int(t.DiscountDays.Int32))
+}
+
+// Computes due or discount date the way GnuCash does: days after the post date
+// or, for proximo terms, a day of the following month
+func (t *BillTerm) computeDate(postDate time.Time, days int) (time.Time, error) {
+	switch BillTermType(t.Type) {
+	case BillTermTypeDays:
+		return postDate.AddDate(0, 0, days), nil
+	case BillTermTypeProximo:
+		year, month, day := postDate.Date()
+
+		// A cutoff <= 0 counts back from the end of the month
+		cutoff := int(t.Cutoff.Int32)
+		if cutoff <= 0 {
+			cutoff += lastDayOfMonth(year, month)
+		}
+
+		// Invoices posted after the cutoff day are due a month later
+		month++
+		if day > cutoff {
+			month++
+		}
+		due := time.Date(year, month, 1, postDate.Hour(), postDate.Minute(),
+			postDate.Second(), 0, postDate.Location())
+
+		if last := lastDayOfMonth(due.Year(), due.Month()); days > last {
+			days = last
+		}
+
+		return due.AddDate(0, 0, days-1), nil
+	}
+
+	return postDate, newError(ErrUnsupported, "billing terms %s have type '%s'", t.Name, t.Type)
+}
+
+func lastDayOfMonth(year int, month time.Month) int {
+	return time.Date(year, month+1, 0, 0, 0, 0, 0, time.UTC).Day()
+}
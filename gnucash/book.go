@@ -2,8 +2,10 @@ package gnucash
 
 import (
 	"database/sql"
-	"log"
+	"fmt"
+	"math/big"
 	"regexp"
+	"strings"
 
 	"github.com/jmoiron/sqlx"
 
@@ -15,15 +17,53 @@ type Book struct {
 	RootAccount *Account
 	Accounts    []*Account
 	DbBook
-	slots        []*Slot
 	lots         []*Lot
 	commodities  []*Commodity
 	vendors      []*Vendor
+	customers    []*Customer
+	jobs         []*Job
 	employees    []*Employee
+	taxTables    []*TaxTable
+	billTerms    []*BillTerm
 	invoices     []*Invoice
 	entries      []*Entry
 	transactions []*Transaction
 	splits       []*Split
+
+	// Indexes for lookups, maintained when objects are added, removed or
+	// written. Name and ID indexes hold the GUIDs of all objects with that
+	// name or ID, lookups return the one indexed first.
+	slotsByObj         map[string][]*Slot // by obj_guid
+	accountsByGuid     map[string]*Account
+	lotsByGuid         map[string]*Lot
+	commoditiesByGuid  map[string]*Commodity
+	commoditiesByName  map[string]*Commodity // by mnemonic
+	vendorsByGuid      map[string]*Vendor
+	vendorsByName      keyIndex
+	vendorsById        keyIndex
+	customersByGuid    map[string]*Customer
+	customersByName    keyIndex
+	customersById      keyIndex
+	jobsByGuid         map[string]*Job
+	jobsByName         keyIndex
+	jobsById           keyIndex
+	employeesByGuid    map[string]*Employee
+	employeesByName    keyIndex // by username
+	employeesById      keyIndex
+	taxTablesByGuid    map[string]*TaxTable
+	taxTablesByName    keyIndex
+	billTermsByGuid    map[string]*BillTerm
+	billTermsByName    keyIndex
+	invoicesByGuid     map[string]*Invoice
+	transactionsByGuid map[string]*Transaction
+	splitsByGuid       map[string]*Split
+	splitsByLot        keyIndex // split GUIDs by lot GUID
+	invoicesByPostTxn  map[string]*Invoice
+
+	options  openOptions
+	versions map[string]int  // by table name, see GetTableVersion
+	lock     *Lock           // taken by Lock
+	subtree  map[string]bool // GUIDs of the accounts selected by LoadAccountSubtree
 }
 
 type DbBook struct {
@@ -32,31 +72,84 @@ type DbBook struct {
 	RootTemplateGuid string `db:"root_template_guid"`
 }
 
-func OpenBookFromSQLite(path string) (*Book, error) {
-	db, _ := sqlx.Open("sqlite3", path)
+func OpenBookFromSQLite(path string, options ...OpenOption) (book *Book, err error) {
+	opts := openOptions{tables: TableAll}
+	for _, o := range options {
+		o(&opts)
+	}
+
+	// Invoices need the credit note slots and the tax tables of their entries
+	if opts.tables&TableInvoices != 0 {
+		opts.tables |= TableSlots | TableTaxTables
+	}
+
+	db, err := sqlx.Open("sqlite3", path)
+	if err != nil {
+		return nil, dbError(err, "opening book %s", path)
+	}
+
+	// Don't leave the database open if the book can't be loaded
+	defer func() {
+		if err != nil {
+			db.Close()
+		}
+	}()
 
 	// Force connection to report potential errors
-	err := db.Ping()
+	err = db.Ping()
+	if err != nil {
+		return nil, dbError(err, "opening book %s", path)
+	}
+
+	versions, err := readVersions(db)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
 
 	var dbBook DbBook
 	err = db.Get(&dbBook, "SELECT * FROM books")
 	if err != nil {
-		log.Fatal(err)
+		return nil, dbError(err, "loading books")
 	}
 
-	book := &Book{
-		DB:     db,
-		DbBook: dbBook,
+	book = &Book{
+		DB:                 db,
+		DbBook:             dbBook,
+		options:            opts,
+		versions:           versions,
+		slotsByObj:         make(map[string][]*Slot),
+		accountsByGuid:     make(map[string]*Account),
+		lotsByGuid:         make(map[string]*Lot),
+		commoditiesByGuid:  make(map[string]*Commodity),
+		commoditiesByName:  make(map[string]*Commodity),
+		vendorsByGuid:      make(map[string]*Vendor),
+		vendorsByName:      make(keyIndex),
+		vendorsById:        make(keyIndex),
+		customersByGuid:    make(map[string]*Customer),
+		customersByName:    make(keyIndex),
+		customersById:      make(keyIndex),
+		jobsByGuid:         make(map[string]*Job),
+		jobsByName:         make(keyIndex),
+		jobsById:           make(keyIndex),
+		employeesByGuid:    make(map[string]*Employee),
+		employeesByName:    make(keyIndex),
+		employeesById:      make(keyIndex),
+		taxTablesByGuid:    make(map[string]*TaxTable),
+		taxTablesByName:    make(keyIndex),
+		billTermsByGuid:    make(map[string]*BillTerm),
+		billTermsByName:    make(keyIndex),
+		invoicesByGuid:     make(map[string]*Invoice),
+		transactionsByGuid: make(map[string]*Transaction),
+		splitsByGuid:       make(map[string]*Split),
+		splitsByLot:        make(keyIndex),
+		invoicesByPostTxn:  make(map[string]*Invoice),
 	}
 
 	// Load accounts
 	as := []DbAccount{}
 	err = db.Select(&as, "SELECT * FROM accounts")
 	if err != nil {
-		log.Fatal(err)
+		return nil, dbError(err, "loading accounts")
 	}
 
 	for _, a := range as {
@@ -67,12 +160,17 @@ func OpenBookFromSQLite(path string) (*Book, error) {
 		}
 
 		book.Accounts = append(book.Accounts, acc)
+		book.accountsByGuid[acc.Guid] = acc
 
 		if a.Guid == dbBook.RootAccountGuid {
 			book.RootAccount = acc
 		}
 	}
 
+	if book.RootAccount == nil {
+		return nil, newError(ErrCorruptBook, "root account %s not found", dbBook.RootAccountGuid)
+	}
+
 	// Resolve parent/child relationships
 	for i := range book.Accounts {
 		if !book.Accounts[i].ParentGuid.Valid {
@@ -81,16 +179,20 @@ func OpenBookFromSQLite(path string) (*Book, error) {
 
 		book.Accounts[i].Parent = book.GetAccountByGUID(
 			book.Accounts[i].ParentGuid.String)
+		if book.Accounts[i].Parent == nil {
+			return nil, newError(ErrCorruptBook, "parent %s of account %s not found",
+				book.Accounts[i].ParentGuid.String, book.Accounts[i].Guid)
+		}
 		book.Accounts[i].Parent.Children =
 			append(book.Accounts[i].Parent.Children, book.Accounts[i])
 	}
 
 	// Load lots
-	{
+	if opts.tables&TableLots != 0 {
 		ls := []DbLot{}
 		err = db.Select(&ls, "SELECT * FROM lots")
 		if err != nil {
-			log.Fatal(err)
+			return nil, dbError(err, "loading lots")
 		}
 
 		for _, dbl := range ls {
@@ -100,30 +202,33 @@ func OpenBookFromSQLite(path string) (*Book, error) {
 			}
 
 			book.lots = append(book.lots, l)
+			book.lotsByGuid[l.Guid] = l
 		}
 	}
 
 	// Load slots
-	ss := []DbSlot{}
-	err = db.Select(&ss, "SELECT * FROM slots")
-	if err != nil {
-		log.Fatal(err)
-	}
-
-	for _, dbs := range ss {
-		s := &Slot{
-			book:   book,
-			DbSlot: dbs,
+	if opts.tables&TableSlots != 0 {
+		ss := []DbSlot{}
+		err = db.Select(&ss, "SELECT * FROM slots")
+		if err != nil {
+			return nil, dbError(err, "loading slots")
 		}
 
-		book.slots = append(book.slots, s)
+		for _, dbs := range ss {
+			s := &Slot{
+				book:   book,
+				DbSlot: dbs,
+			}
+
+			book.slotsByObj[s.ObjGuid] = append(book.slotsByObj[s.ObjGuid], s)
+		}
 	}
 
 	// Load commodities
 	cs := []DbCommodity{}
 	err = db.Select(&cs, "SELECT * FROM commodities")
 	if err != nil {
-		log.Fatal(err)
+		return nil, dbError(err, "loading commodities")
 	}
 
 	for _, dbc := range cs {
@@ -131,28 +236,75 @@ func OpenBookFromSQLite(path string) (*Book, error) {
 			dbc,
 		}
 		book.commodities = append(book.commodities, c)
+		book.commoditiesByGuid[c.Guid] = c
+		if _, ok := book.commoditiesByName[c.Mnemonic]; !ok {
+			book.commoditiesByName[c.Mnemonic] = c
+		}
 	}
 
 	// Load vendors
-	vs := []DbVendor{}
-	err = db.Select(&vs, "SELECT * FROM vendors")
-	if err != nil {
-		log.Fatal(err)
+	if opts.tables&TableVendors != 0 {
+		vs := []DbVendor{}
+		err = db.Select(&vs, "SELECT * FROM vendors")
+		if err != nil {
+			return nil, dbError(err, "loading vendors")
+		}
+
+		for _, dbv := range vs {
+			v := &Vendor{
+				book:     book,
+				DbVendor: dbv,
+			}
+			book.vendors = append(book.vendors, v)
+			book.vendorsByGuid[v.Guid] = v
+			v.index()
+		}
 	}
 
-	for _, dbv := range vs {
-		v := &Vendor{
-			dbv,
+	// Load customers
+	if opts.tables&TableCustomers != 0 {
+		cs := []DbCustomer{}
+		err = db.Select(&cs, "SELECT * FROM customers")
+		if err != nil {
+			return nil, dbError(err, "loading customers")
+		}
+
+		for _, dbc := range cs {
+			c := &Customer{
+				book:       book,
+				DbCustomer: dbc,
+			}
+			book.customers = append(book.customers, c)
+			book.customersByGuid[c.Guid] = c
+			c.index()
+		}
+	}
+
+	// Load jobs
+	if opts.tables&TableJobs != 0 {
+		js := []DbJob{}
+		err = db.Select(&js, "SELECT * FROM jobs")
+		if err != nil {
+			return nil, dbError(err, "loading jobs")
+		}
+
+		for _, dbj := range js {
+			j := &Job{
+				book:  book,
+				DbJob: dbj,
+			}
+			book.jobs = append(book.jobs, j)
+			book.jobsByGuid[j.Guid] = j
+			j.index()
 		}
-		book.vendors = append(book.vendors, v)
 	}
 
 	// Load employees
-	{
+	if opts.tables&TableEmployees != 0 {
 		es := []DbEmployee{}
 		err = db.Select(&es, "SELECT * FROM employees")
 		if err != nil {
-			log.Fatal(err)
+			return nil, dbError(err, "loading employees")
 		}
 
 		for _, dbe := range es {
@@ -161,57 +313,184 @@ func OpenBookFromSQLite(path string) (*Book, error) {
 				DbEmployee: dbe,
 			}
 			book.employees = append(book.employees, e)
+			book.employeesByGuid[e.Guid] = e
+			e.index()
 		}
 	}
 
-	// Load invoices
-	is := []DbInvoice{}
-	err = db.Select(&is, "SELECT * FROM invoices")
-	if err != nil {
-		log.Fatal(err)
+	// Load tax tables
+	if opts.tables&TableTaxTables != 0 {
+		ts := []DbTaxTable{}
+		err = db.Select(&ts, "SELECT * FROM taxtables")
+		if err != nil {
+			return nil, dbError(err, "loading taxtables")
+		}
+
+		for _, dbt := range ts {
+			t := &TaxTable{
+				book:       book,
+				DbTaxTable: dbt,
+			}
+			book.taxTables = append(book.taxTables, t)
+			book.taxTablesByGuid[t.Guid] = t
+			t.index()
+		}
+
+		tes := []DbTaxTableEntry{}
+		err = db.Select(&tes, "SELECT * FROM taxtable_entries ORDER BY id")
+		if err != nil {
+			return nil, dbError(err, "loading taxtable_entries")
+		}
+
+		for _, dbte := range tes {
+			t := book.GetTaxTableByGUID(dbte.TaxTable)
+			if t == nil {
+				return nil, newError(ErrCorruptBook, "tax table %s of tax table entry %d not found",
+					dbte.TaxTable, dbte.Id)
+			}
+
+			te := &TaxTableEntry{
+				book:            book,
+				DbTaxTableEntry: dbte,
+				Account:         book.GetAccountByGUID(dbte.AccountGuid),
+			}
+			if te.Account == nil {
+				return nil, newError(ErrCorruptBook, "account %s of tax table %s not found",
+					dbte.AccountGuid, t.Name)
+			}
+
+			t.Entries = append(t.Entries, te)
+		}
+	}
+
+	// Load billing terms
+	if opts.tables&TableBillTerms != 0 {
+		ts := []DbBillTerm{}
+		err = db.Select(&ts, "SELECT * FROM billterms")
+		if err != nil {
+			return nil, dbError(err, "loading billterms")
+		}
+
+		for _, dbt := range ts {
+			t := &BillTerm{
+				book:       book,
+				DbBillTerm: dbt,
+			}
+			book.billTerms = append(book.billTerms, t)
+			book.billTermsByGuid[t.Guid] = t
+			t.index()
+		}
+	}
+
+	// Load invoices and (unless loading lazily) their entries
+	if opts.tables&TableInvoices != 0 {
+		is := []DbInvoice{}
+		err = db.Select(&is, "SELECT * FROM invoices")
+		if err != nil {
+			return nil, dbError(err, "loading invoices")
+		}
+
+		for _, dbi := range is {
+			cnslot := book.getSlotForObjByName(dbi.Guid, "credit-note")
+			if cnslot == nil {
+				return nil, newError(ErrCorruptBook, "credit note slot of invoice %s not found", dbi.Guid)
+			}
+			if cnslot.SlotType != int(SlotTypeInt64) {
+				return nil, newError(ErrCorruptBook, "credit note slot of invoice %s has wrong type",
+					dbi.Guid)
+			}
+			if !cnslot.Int64Val.Valid {
+				return nil, newError(ErrCorruptBook, "credit note slot of invoice %s has null value",
+					dbi.Guid)
+			}
+
+			i := &Invoice{
+				book:         book,
+				IsCreditNote: (cnslot.Int64Val.Int64 != 0),
+				DbInvoice:    dbi,
+			}
+
+			book.invoices = append(book.invoices, i)
+			book.invoicesByGuid[i.Guid] = i
+			i.indexPostTxn()
+		}
+
+		if !opts.lazy {
+			err = book.loadEntries("1")
+			if err != nil {
+				return nil, err
+			}
+
+			for _, i := range book.invoices {
+				i.entriesLoaded = true
+			}
+		}
 	}
 
-	for _, dbi := range is {
-		cnslot := book.getSlotForObjByName(dbi.Guid, "credit-note")
-		if cnslot == nil {
-			log.Fatalf("Credit note slot for invoice %s not found\n", dbi.Guid)
+	// Resolve the account subtree, even when loading lazily, so that an
+	// unknown account is reported right away
+	if opts.account != "" {
+		acc := book.RootAccount.GetAccountByPath(opts.account)
+		if acc == nil {
+			return nil, newError(ErrNotFound, "account %s not found", opts.account)
 		}
-		if cnslot.SlotType != int(SlotTypeInt64) {
-			log.Fatalf("Credit note slot for invoice %s has wrong type\n",
-				dbi.Guid)
+
+		book.subtree = map[string]bool{acc.Guid: true}
+		for _, a := range acc.GetDescendants() {
+			book.subtree[a.Guid] = true
 		}
-		if !cnslot.Int64Val.Valid {
-			log.Fatalf("Credit note slot for invoice %s has null value\n",
-				dbi.Guid)
+	}
+
+	// Load transactions and their splits unless loading lazily
+	if opts.tables&TableTransactions != 0 && !opts.lazy {
+		for _, a := range book.Accounts {
+			a.splitsLoaded = true
 		}
 
-		i := &Invoice{
-			book:         book,
-			IsCreditNote: (cnslot.Int64Val.Int64 != 0),
-			DbInvoice:    dbi,
+		cond, args := opts.transactionCondition(book.subtreeGuids())
+		err = book.loadTransactions(cond, args...)
+		if err != nil {
+			return nil, err
 		}
+	}
+
+	return book, nil
+}
+
+// Returns the GUIDs of the accounts in the subtree selected by
+// LoadAccountSubtree, nil if the whole tree is loaded
+func (b *Book) subtreeGuids() []string {
+	if b.subtree == nil {
+		return nil
+	}
 
-		book.invoices = append(book.invoices, i)
+	guids := []string{}
+	for guid := range b.subtree {
+		guids = append(guids, guid)
 	}
 
-	// Load entries
+	return guids
+}
+
+// Loads the entries matching cond and adds them to their invoices
+func (b *Book) loadEntries(cond string, args ...interface{}) error {
 	es := []DbEntry{}
-	err = db.Select(&es, "SELECT * FROM entries")
+	err := b.DB.Select(&es, "SELECT * FROM entries WHERE "+cond, args...)
 	if err != nil {
-		log.Fatal(err)
+		return dbError(err, "loading entries")
 	}
 
 	for _, dbe := range es {
 		e := &Entry{
-			book:    book,
+			book:    b,
 			DbEntry: dbe,
 		}
 
 		// Find associated invoice
 		if dbe.Invoice.Valid {
-			invoice := book.GetInvoiceByGUID(dbe.Invoice.String)
+			invoice := b.GetInvoiceByGUID(dbe.Invoice.String)
 			if invoice == nil {
-				log.Fatalf("Invoice %s not found for entry %s\n",
+				return newError(ErrCorruptBook, "invoice %s of entry %s not found",
 					dbe.Invoice.String, dbe.Guid)
 			}
 
@@ -219,137 +498,163 @@ func OpenBookFromSQLite(path string) (*Book, error) {
 			invoice.Entries = append(invoice.Entries, e)
 		}
 
-		book.entries = append(book.entries, e)
-	}
+		// Find associated bill (or expense voucher)
+		if dbe.Bill.Valid {
+			bill := b.GetInvoiceByGUID(dbe.Bill.String)
+			if bill == nil {
+				return newError(ErrCorruptBook, "bill %s of entry %s not found",
+					dbe.Bill.String, dbe.Guid)
+			}
 
-	// Load transactions
-	{
-		ts := []DbTransaction{}
-		err = db.Select(&ts, "SELECT * FROM transactions")
-		if err != nil {
-			log.Fatal(err)
+			bill.Entries = append(bill.Entries, e)
 		}
 
-		for _, dbt := range ts {
-			t := &Transaction{
-				book:          book,
-				DbTransaction: dbt,
-			}
+		b.entries = append(b.entries, e)
+	}
 
-			book.transactions = append(book.transactions, t)
-		}
+	return nil
+}
+
+// Loads the transactions (as t) matching cond with all of their splits,
+// skipping those already loaded. Splits are added to the accounts whose splits
+// are loaded.
+func (b *Book) loadTransactions(cond string, args ...interface{}) error {
+	ts := []DbTransaction{}
+	err := b.DB.Select(&ts, "SELECT * FROM transactions t WHERE "+cond, args...)
+	if err != nil {
+		return dbError(err, "loading transactions")
 	}
 
-	// Load splits
-	{
-		ss := []DbSplit{}
-		err = db.Select(&ss, "SELECT * FROM splits")
-		if err != nil {
-			log.Fatal(err)
+	for _, dbt := range ts {
+		if b.transactionsByGuid[dbt.Guid] != nil {
+			continue
 		}
 
-		for _, dbs := range ss {
-			s := &Split{
-				book:    book,
-				DbSplit: dbs,
-			}
+		t := &Transaction{
+			book:          b,
+			DbTransaction: dbt,
+		}
 
-			// Find associated transaction and connect
-			txn := book.GetTransactionByGUID(s.TxGuid)
-			if txn == nil {
-				log.Fatalf("Associated transaction %s for split %s not found\n",
-					s.TxGuid, s.Guid)
-			}
+		b.transactions = append(b.transactions, t)
+		b.transactionsByGuid[t.Guid] = t
+	}
 
-			s.Transaction = txn
-			txn.Splits = append(txn.Splits, s)
+	ss := []DbSplit{}
+	err = b.DB.Select(&ss, `SELECT * FROM splits WHERE tx_guid IN
+		(SELECT t.guid FROM transactions t WHERE `+cond+`)`, args...)
+	if err != nil {
+		return dbError(err, "loading splits")
+	}
 
-			// Find associated account and connect
-			acc := book.GetAccountByGUID(s.AccountGuid)
-			if acc == nil {
-				log.Fatalf("Associated account %s for split %s not found\n",
-					s.AccountGuid, s.Guid)
-			}
+	for _, dbs := range ss {
+		if b.splitsByGuid[dbs.Guid] != nil {
+			continue
+		}
 
-			acc.Splits = append(acc.Splits, s)
-			s.Account = acc
+		s := &Split{
+			book:    b,
+			DbSplit: dbs,
+		}
 
-			book.splits = append(book.splits, s)
+		// Find associated transaction and connect
+		txn := b.GetTransactionByGUID(s.TxGuid)
+		if txn == nil {
+			return newError(ErrCorruptBook, "transaction %s of split %s not found",
+				s.TxGuid, s.Guid)
 		}
-	}
 
-	return book, nil
-}
+		s.Transaction = txn
+		txn.Splits = append(txn.Splits, s)
 
-func (b *Book) GetAccountByGUID(guid string) *Account {
-	for _, acc := range b.Accounts {
-		if acc.Guid == guid {
-			return acc
+		// Find associated account and connect
+		acc := b.GetAccountByGUID(s.AccountGuid)
+		if acc == nil {
+			return newError(ErrCorruptBook, "account %s of split %s not found",
+				s.AccountGuid, s.Guid)
+		}
+
+		if acc.splitsLoaded {
+			acc.Splits = append(acc.Splits, s)
 		}
+		s.Account = acc
+
+		b.splits = append(b.splits, s)
+		b.splitsByGuid[s.Guid] = s
+		s.indexLot()
 	}
 
 	return nil
 }
 
+func (b *Book) GetAccountByGUID(guid string) *Account {
+	return b.accountsByGuid[guid]
+}
+
 func (b *Book) GetTransactionByGUID(guid string) *Transaction {
-	for _, t := range b.transactions {
-		if t.Guid == guid {
-			return t
-		}
-	}
+	return b.transactionsByGuid[guid]
+}
 
-	return nil
+func (b *Book) GetSplitByGUID(guid string) *Split {
+	return b.splitsByGuid[guid]
 }
 
 func (b *Book) GetLotByGUID(guid string) *Lot {
-	for _, l := range b.lots {
-		if l.Guid == guid {
-			return l
-		}
-	}
-
-	return nil
+	return b.lotsByGuid[guid]
 }
 
 func (b *Book) GetAccountByPath(path string) *Account {
 	return b.RootAccount.GetAccountByPath(path)
 }
 
-func (b *Book) incrementCounter(name string) int64 {
+// Increments counter name and returns its new value. Like in GnuCash, counters
+// hold the last number issued, so the new value is the number to issue next.
+func (b *Book) incrementCounter(name string) (int64, error) {
+	// Make sure counter slot exists
+	n, err := b.getCounter(name)
+	if err != nil {
+		return -1, err
+	}
+
 	s := b.getSlotByName(name)
-	if s != nil && s.Int64Val.Valid {
-		ret := s.Int64Val.Int64
-		s.Int64Val.Int64 = s.Int64Val.Int64 + 1
-		s.Write()
-		return ret
+	s.Int64Val.Int64 = n + 1
+	return n + 1, s.Write()
+}
+
+func (b *Book) setCounter(name string, val int64) error {
+	// Make sure counter slot exists
+	if _, err := b.getCounter(name); err != nil {
+		return err
 	}
 
-	return -1
+	s := b.getSlotByName(name)
+	s.Int64Val = sql.NullInt64{val, true}
+	return s.Write()
 }
 
-func (b *Book) incrementBillCounter() int64 {
+func (b *Book) incrementBillCounter() (int64, error) {
 	return b.incrementCounter("counters/gncBill")
 }
 
-func (b *Book) incrementInvoiceCounter() int64 {
+func (b *Book) incrementInvoiceCounter() (int64, error) {
 	return b.incrementCounter("counters/gncInvoice")
 }
 
-func (b *Book) incrementExpenseVoucherCounter() int64 {
+func (b *Book) incrementExpenseVoucherCounter() (int64, error) {
 	return b.incrementCounter("counters/gncExpVoucher")
 }
 
-func (b *Book) AddSlotIfNotExist(s *Slot) *Slot {
+// Adds the book slot s unless the book already has a slot of that name, which
+// is returned instead
+func (b *Book) AddSlotIfNotExist(s *Slot) (*Slot, error) {
 	existing := b.getSlotByName(s.Name)
 	if existing != nil {
-		return existing
+		return existing, nil
 	}
 
-	b.AddSlot(s)
-	return s
+	return s, b.AddSlot(s)
 }
 
-func (b *Book) getCounterFormat(name string) string {
+func (b *Book) getCounterFormat(name string) (string, error) {
 	// Ensure 'counter_formats' slot exists
 	cfs := &Slot{
 		DbSlot: DbSlot{
@@ -359,7 +664,10 @@ func (b *Book) getCounterFormat(name string) string {
 			GuidVal:  sql.NullString{NewGuid(), true},
 		},
 	}
-	cfs = b.AddSlotIfNotExist(cfs)
+	cfs, err := b.AddSlotIfNotExist(cfs)
+	if err != nil {
+		return "", err
+	}
 
 	s := &Slot{
 		DbSlot: DbSlot{
@@ -369,17 +677,21 @@ func (b *Book) getCounterFormat(name string) string {
 			StringVal: sql.NullString{"%05li", true},
 		},
 	}
-	s = b.AddSlotIfNotExist(s)
-	if s != nil && s.StringVal.Valid {
-		// Replace 'li' with 'd' (as Go's printf doesn't seem to support li)
-		r := regexp.MustCompile(`(%-?\d*)li`)
-		return r.ReplaceAllString(s.StringVal.String, "${1}d")
+	s, err = b.AddSlotIfNotExist(s)
+	if err != nil {
+		return "", err
 	}
 
-	return ""
+	if !s.StringVal.Valid {
+		return "", newError(ErrCorruptBook, "counter format %s is null", name)
+	}
+
+	// Replace 'li' with 'd' (as Go's printf doesn't seem to support li)
+	r := regexp.MustCompile(`(%-?\d*)li`)
+	return r.ReplaceAllString(s.StringVal.String, "${1}d"), nil
 }
 
-func (b *Book) getCounter(name string) int64 {
+func (b *Book) getCounter(name string) (int64, error) {
 	// Ensure 'counters' slot exists
 	cfs := &Slot{
 		DbSlot: DbSlot{
@@ -389,7 +701,10 @@ func (b *Book) getCounter(name string) int64 {
 			GuidVal:  sql.NullString{NewGuid(), true},
 		},
 	}
-	cfs = b.AddSlotIfNotExist(cfs)
+	cfs, err := b.AddSlotIfNotExist(cfs)
+	if err != nil {
+		return -1, err
+	}
 
 	s := &Slot{
 		DbSlot: DbSlot{
@@ -399,58 +714,116 @@ func (b *Book) getCounter(name string) int64 {
 			Int64Val: sql.NullInt64{0, true},
 		},
 	}
-	s = b.AddSlotIfNotExist(s)
+	s, err = b.AddSlotIfNotExist(s)
+	if err != nil {
+		return -1, err
+	}
 
-	if s != nil && s.Int64Val.Valid {
-		return s.Int64Val.Int64
+	if !s.Int64Val.Valid {
+		return -1, newError(ErrCorruptBook, "counter %s is null", name)
 	}
 
-	return -1
+	return s.Int64Val.Int64, nil
 }
 
-func (b *Book) GetBillCounterFormat() string {
+func (b *Book) GetBillCounterFormat() (string, error) {
 	return b.getCounterFormat("counter_formats/gncBill")
 }
 
-func (b *Book) GetInvoiceCounterFormat() string {
+func (b *Book) GetInvoiceCounterFormat() (string, error) {
 	return b.getCounterFormat("counter_formats/gncInvoice")
 }
 
-func (b *Book) GetExpenseVoucherCounterFormat() string {
+func (b *Book) GetExpenseVoucherCounterFormat() (string, error) {
 	return b.getCounterFormat("counter_formats/gncExpVoucher")
 }
 
-func (b *Book) GetBillCounter() int64 {
+func (b *Book) GetCustomerCounterFormat() (string, error) {
+	return b.getCounterFormat("counter_formats/gncCustomer")
+}
+
+func (b *Book) GetJobCounterFormat() (string, error) {
+	return b.getCounterFormat("counter_formats/gncJob")
+}
+
+func (b *Book) GetVendorCounterFormat() (string, error) {
+	return b.getCounterFormat("counter_formats/gncVendor")
+}
+
+func (b *Book) GetEmployeeCounterFormat() (string, error) {
+	return b.getCounterFormat("counter_formats/gncEmployee")
+}
+
+func (b *Book) GetBillCounter() (int64, error) {
 	return b.getCounter("counters/gncBill")
 }
 
-func (b *Book) GetInvoiceCounter() int64 {
+func (b *Book) GetInvoiceCounter() (int64, error) {
 	return b.getCounter("counters/gncInvoice")
 }
 
-func (b *Book) GetExpenseVoucherCounter() int64 {
+func (b *Book) GetExpenseVoucherCounter() (int64, error) {
 	return b.getCounter("counters/gncExpVoucher")
 }
 
+func (b *Book) GetCustomerCounter() (int64, error) {
+	return b.getCounter("counters/gncCustomer")
+}
+
+func (b *Book) GetJobCounter() (int64, error) {
+	return b.getCounter("counters/gncJob")
+}
+
+func (b *Book) GetVendorCounter() (int64, error) {
+	return b.getCounter("counters/gncVendor")
+}
+
+func (b *Book) GetEmployeeCounter() (int64, error) {
+	return b.getCounter("counters/gncEmployee")
+}
+
+// Returns the ID the next bill gets, the counter is incremented when it is
+// added by AddInvoice
+func (b *Book) GetNextBillId() (string, error) {
+	return b.peekNextId("gncBill")
+}
+
+// Returns the ID the next customer invoice gets, the counter is incremented
+// when it is added by AddInvoice
+func (b *Book) GetNextInvoiceId() (string, error) {
+	return b.peekNextId("gncInvoice")
+}
+
+// Returns the ID the next expense voucher gets, the counter is incremented
+// when it is added by AddInvoice
+func (b *Book) GetNextExpenseVoucherId() (string, error) {
+	return b.peekNextId("gncExpVoucher")
+}
+
+func (b *Book) SetBillCounter(val int64) error {
+	return b.setCounter("counters/gncBill", val)
+}
+
+func (b *Book) SetInvoiceCounter(val int64) error {
+	return b.setCounter("counters/gncInvoice", val)
+}
+
+func (b *Book) SetExpenseVoucherCounter(val int64) error {
+	return b.setCounter("counters/gncExpVoucher", val)
+}
+
+// Returns the book slot at path name (e.g. "counters/gncBill"), also finding
+// slots stored directly on the book under their full path
 func (b *Book) getSlotByName(name string) *Slot {
-	for _, s := range b.slots {
-		if s.Name == name {
-			return s
-		}
+	if s := b.GetSlot(b.Guid, name); s != nil {
+		return s
 	}
 
-	return nil
+	return b.getSlotForObjByName(b.Guid, name)
 }
 
 func (b *Book) getSlotsByObjGUID(guid string) []*Slot {
-	slots := []*Slot{}
-	for _, s := range b.slots {
-		if s.ObjGuid == guid {
-			slots = append(slots, s)
-		}
-	}
-
-	return slots
+	return append([]*Slot{}, b.slotsByObj[guid]...)
 }
 
 func (b *Book) getSlotForObjByName(objGuid string, slotName string) *Slot {
@@ -464,22 +837,44 @@ func (b *Book) getSlotForObjByName(objGuid string, slotName string) *Slot {
 	return nil
 }
 
-func (b *Book) AddInvoice(i *Invoice) {
+func (b *Book) AddInvoice(i *Invoice) error {
+	if err := b.checkComplete("adding an invoice"); err != nil {
+		return err
+	}
+
 	// Generate Guid if not set
 	if i.Guid == "" {
 		i.Guid = NewGuid()
 	}
 
-	// Generate Id if not set
-	if i.Id == "" {
-		switch i.GetOwnerType() {
-		case OwnerTypeVendor:
-			b.GetBillCounter()
+	i.book = b
+	i.entriesLoaded = true
+
+	// Find counter to increment
+	var increment func() (int64, error)
+	switch i.GetEndOwnerType() {
+	case OwnerTypeVendor:
+		increment = b.incrementBillCounter
+	case OwnerTypeCustomer:
+		increment = b.incrementInvoiceCounter
+	case OwnerTypeEmployee:
+		increment = b.incrementExpenseVoucherCounter
+	default:
+		return newError(ErrUnsupported, "adding invoice %s with owner type %d", i.Id, i.GetEndOwnerType())
+	}
+
+	// Documents get the default billing terms of their owner (as in GnuCash)
+	if !i.Terms.Valid {
+		if v := i.GetVendor(); v != nil {
+			i.Terms = v.Terms
+		} else if c := i.GetCustomer(); c != nil {
+			i.Terms = c.Terms
 		}
 	}
 
-	i.book = b
-	i.Write()
+	if err := i.Write(); err != nil {
+		return err
+	}
 
 	// Create credit-note slot indicating whether this invoice is a credit note
 	slotVal := 0
@@ -495,86 +890,155 @@ func (b *Book) AddInvoice(i *Invoice) {
 			Int64Val: sql.NullInt64{int64(slotVal), true},
 		},
 	}
-	b.AddSlot(s)
-
-	// Increment correct counter
-	switch i.GetOwnerType() {
-	case OwnerTypeVendor:
-		b.incrementBillCounter()
-	case OwnerTypeCustomer:
-		b.incrementInvoiceCounter()
-	case OwnerTypeEmployee:
-		b.incrementExpenseVoucherCounter()
-	default:
-		log.Fatal("Unknown invoice type not implemented")
+	if err := b.AddSlot(s); err != nil {
+		return err
 	}
-
 	b.invoices = append(b.invoices, i)
+	b.invoicesByGuid[i.Guid] = i
+
+	_, err := increment()
+	return err
 }
 
-func (b *Book) GetInvoiceByGUID(guid string) *Invoice {
-	for _, i := range b.invoices {
-		if i.Guid == guid {
-			return i
-		}
-	}
+func (b *Book) GetInvoices() []*Invoice {
+	return b.invoices
+}
 
-	return nil
+func (b *Book) GetInvoiceByGUID(guid string) *Invoice {
+	return b.invoicesByGuid[guid]
 }
 
-func (b *Book) AddSlot(s *Slot) {
+func (b *Book) AddSlot(s *Slot) error {
 	s.book = b
-	s.create()
-	b.slots = append(b.slots, s)
+	if err := s.create(); err != nil {
+		return err
+	}
+	b.slotsByObj[s.ObjGuid] = append(b.slotsByObj[s.ObjGuid], s)
+
+	return nil
 }
 
-func (b *Book) RemoveSlot(s *Slot) {
-	// Remove from book slots slice
-	for i, x := range b.slots {
+func (b *Book) RemoveSlot(s *Slot) error {
+	// Remove from book slots index
+	slots := b.slotsByObj[s.ObjGuid]
+	for i, x := range slots {
 		if x == s {
-			b.slots[i] = b.slots[len(b.slots)-1]
+			slots = append(slots[:i], slots[i+1:]...)
 			break
 		}
 	}
-	b.slots = b.slots[:len(b.slots)-1]
+	if len(slots) == 0 {
+		delete(b.slotsByObj, s.ObjGuid)
+	} else {
+		b.slotsByObj[s.ObjGuid] = slots
+	}
 
 	// Remove from database
-	s.remove()
+	return s.remove()
 }
 
-func (b *Book) AddTransaction(t *Transaction) {
+// Removes all slots of the object with the given GUID, including the contents
+// of frame slots
+func (b *Book) removeSlotsForObj(guid string) error {
+	for _, s := range b.getSlotsByObjGUID(guid) {
+		if err := b.removeSlotTree(s); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (b *Book) AddTransaction(t *Transaction) error {
+	if err := b.checkComplete("adding a transaction"); err != nil {
+		return err
+	}
+
 	if t.Guid == "" {
 		t.Guid = NewGuid()
 	}
 
 	t.book = b
-	t.write()
+	if err := t.write(); err != nil {
+		return err
+	}
 	b.transactions = append(b.transactions, t)
+	b.transactionsByGuid[t.Guid] = t
+
+	return nil
 }
 
 func (b *Book) GetCommodityByGUID(guid string) *Commodity {
-	for _, c := range b.commodities {
-		if c.Guid == guid {
-			return c
-		}
-	}
-
-	return nil
+	return b.commoditiesByGuid[guid]
 }
 
 func (b *Book) GetCommodityByMnemonic(mnemonic string) *Commodity {
-	for _, c := range b.commodities {
-		if c.Mnemonic == mnemonic {
-			return c
+	return b.commoditiesByName[mnemonic]
+}
+
+// Adds a new employee to the book. GUID and ID are generated if not set, as are
+// currency (the default currency), workday and rate.
+func (b *Book) AddEmployee(e *Employee) error {
+	if e.Username == "" {
+		return newError(ErrConstraint, "adding employee without username")
+	}
+
+	if o := b.GetEmployeeByUsername(e.Username); o != nil && o != e {
+		return newError(ErrConstraint, "employee with username '%s' already exists", e.Username)
+	}
+
+	if e.Guid == "" {
+		e.Guid = NewGuid()
+	}
+
+	if e.Id == "" {
+		id, err := b.nextId("gncEmployee")
+		if err != nil {
+			return err
+		}
+		e.Id = id
+	}
+
+	if e.Currency == "" {
+		curr, err := b.GetDefaultCurrency()
+		if err != nil {
+			return err
 		}
+		e.Currency = curr.Guid
 	}
 
+	// Workday and rate are mandatory, GnuCash uses zero for both by default
+	if !e.WorkdayNum.Valid {
+		e.SetWorkday(new(big.Rat))
+	}
+
+	if !e.RateNum.Valid {
+		e.SetRate(new(big.Rat))
+	}
+
+	e.book = b
+	if err := e.Write(); err != nil {
+		return err
+	}
+	b.employees = append(b.employees, e)
+	b.employeesByGuid[e.Guid] = e
+
 	return nil
 }
 
-func (b *Book) GetEmployeeByUsername(username string) *Employee {
+func (b *Book) GetEmployees() []*Employee {
+	return b.employees
+}
+
+func (b *Book) GetEmployeeByID(id string) *Employee {
+	return b.employeesByGuid[b.employeesById.first(id)]
+}
+
+// Returns the employee with the given email address (compared case
+// insensitively)
+func (b *Book) GetEmployeeByEmail(email string) *Employee {
 	for _, e := range b.employees {
-		if e.Username == username {
+		if e.AddrEmail.Valid && strings.EqualFold(e.AddrEmail.String, email) {
 			return e
 		}
 	}
@@ -582,6 +1046,10 @@ func (b *Book) GetEmployeeByUsername(username string) *Employee {
 	return nil
 }
 
+func (b *Book) GetEmployeeByUsername(username string) *Employee {
+	return b.employeesByGuid[b.employeesByName.first(username)]
+}
+
 func (b *Book) GetEmployeeByName(name string) *Employee {
 	for _, e := range b.employees {
 		if e.AddrName.Valid && e.AddrName.String == name {
@@ -593,18 +1061,66 @@ func (b *Book) GetEmployeeByName(name string) *Employee {
 }
 
 func (b *Book) GetEmployeeByGUID(guid string) *Employee {
-	for _, e := range b.employees {
-		if e.Guid == guid {
-			return e
+	return b.employeesByGuid[guid]
+}
+
+// Adds a new vendor to the book. GUID and ID are generated if not set, as is
+// the currency (the default currency).
+func (b *Book) AddVendor(v *Vendor) error {
+	if v.Name == "" {
+		return newError(ErrConstraint, "adding vendor without name")
+	}
+
+	if o := b.GetVendorByName(v.Name); o != nil && o != v {
+		return newError(ErrConstraint, "vendor with name '%s' already exists", v.Name)
+	}
+
+	if v.Guid == "" {
+		v.Guid = NewGuid()
+	}
+
+	if v.Id == "" {
+		id, err := b.nextId("gncVendor")
+		if err != nil {
+			return err
+		}
+		v.Id = id
+	}
+
+	if v.Currency == "" {
+		curr, err := b.GetDefaultCurrency()
+		if err != nil {
+			return err
 		}
+		v.Currency = curr.Guid
 	}
 
+	v.book = b
+	if err := v.Write(); err != nil {
+		return err
+	}
+	b.vendors = append(b.vendors, v)
+	b.vendorsByGuid[v.Guid] = v
+
 	return nil
 }
 
+func (b *Book) GetVendors() []*Vendor {
+	return b.vendors
+}
+
 func (b *Book) GetVendorByName(name string) *Vendor {
+	return b.vendorsByGuid[b.vendorsByName.first(name)]
+}
+
+func (b *Book) GetVendorByID(id string) *Vendor {
+	return b.vendorsByGuid[b.vendorsById.first(id)]
+}
+
+// Returns the vendor with the given alias (see Vendor.AddAlias)
+func (b *Book) GetVendorByAlias(alias string) *Vendor {
 	for _, v := range b.vendors {
-		if v.Name == name {
+		if v.HasAlias(alias) {
 			return v
 		}
 	}
@@ -613,24 +1129,255 @@ func (b *Book) GetVendorByName(name string) *Vendor {
 }
 
 func (b *Book) GetVendorByGUID(guid string) *Vendor {
-	for _, v := range b.vendors {
-		if v.Guid == guid {
-			return v
+	return b.vendorsByGuid[guid]
+}
+
+// Adds a new customer to the book. GUID and ID are generated if not set, as are
+// currency (the default currency) and denominators of discount and credit
+// limit.
+func (b *Book) AddCustomer(c *Customer) error {
+	if c.Name == "" {
+		return newError(ErrConstraint, "adding customer without name")
+	}
+
+	if o := b.GetCustomerByName(c.Name); o != nil && o != c {
+		return newError(ErrConstraint, "customer with name '%s' already exists", c.Name)
+	}
+
+	if c.Guid == "" {
+		c.Guid = NewGuid()
+	}
+
+	if c.Id == "" {
+		id, err := b.nextId("gncCustomer")
+		if err != nil {
+			return err
 		}
+		c.Id = id
 	}
 
+	if c.Currency == "" {
+		curr, err := b.GetDefaultCurrency()
+		if err != nil {
+			return err
+		}
+		c.Currency = curr.Guid
+	}
+
+	if c.DiscountDenom == 0 {
+		c.DiscountDenom = 1
+	}
+
+	if c.CreditDenom == 0 {
+		c.CreditDenom = 1
+	}
+
+	c.book = b
+	if err := c.Write(); err != nil {
+		return err
+	}
+	b.customers = append(b.customers, c)
+	b.customersByGuid[c.Guid] = c
+
+	return nil
+}
+
+func (b *Book) GetCustomers() []*Customer {
+	return b.customers
+}
+
+func (b *Book) GetCustomerByName(name string) *Customer {
+	return b.customersByGuid[b.customersByName.first(name)]
+}
+
+func (b *Book) GetCustomerByID(id string) *Customer {
+	return b.customersByGuid[b.customersById.first(id)]
+}
+
+func (b *Book) GetCustomerByGUID(guid string) *Customer {
+	return b.customersByGuid[guid]
+}
+
+// Adds a new job of a customer or vendor (set via Job.SetOwner) to the book.
+// GUID and ID are generated if not set.
+func (b *Book) AddJob(j *Job) error {
+	if j.GetOwnerType() != OwnerTypeCustomer && j.GetOwnerType() != OwnerTypeVendor {
+		return newError(ErrConstraint, "job %s has to be owned by a customer or vendor", j.Name)
+	}
+
+	j.book = b
+	if j.GetCustomer() == nil && j.GetVendor() == nil {
+		return newError(ErrNotFound, "owner %s of job %s", j.OwnerGuid.String, j.Name)
+	}
+
+	if j.Guid == "" {
+		j.Guid = NewGuid()
+	}
+
+	if j.Id == "" {
+		id, err := b.nextId("gncJob")
+		if err != nil {
+			return err
+		}
+		j.Id = id
+	}
+
+	if err := j.Write(); err != nil {
+		return err
+	}
+	b.jobs = append(b.jobs, j)
+	b.jobsByGuid[j.Guid] = j
+
+	return nil
+}
+
+func (b *Book) GetJobs() []*Job {
+	return b.jobs
+}
+
+func (b *Book) GetJobByName(name string) *Job {
+	return b.jobsByGuid[b.jobsByName.first(name)]
+}
+
+func (b *Book) GetJobByID(id string) *Job {
+	return b.jobsByGuid[b.jobsById.first(id)]
+}
+
+func (b *Book) GetJobByGUID(guid string) *Job {
+	return b.jobsByGuid[guid]
+}
+
+// Adds a new (empty) tax table to the book, its GUID is generated if not set.
+// Taxes are added with TaxTable.AddEntry.
+func (b *Book) AddTaxTable(t *TaxTable) error {
+	if t.Name == "" {
+		return newError(ErrConstraint, "adding tax table without name")
+	}
+
+	if o := b.GetTaxTableByName(t.Name); o != nil && o != t {
+		return newError(ErrConstraint, "tax table '%s' already exists", t.Name)
+	}
+
+	if t.Guid == "" {
+		t.Guid = NewGuid()
+	}
+
+	t.book = b
+	if err := t.write(); err != nil {
+		return err
+	}
+	b.taxTables = append(b.taxTables, t)
+	b.taxTablesByGuid[t.Guid] = t
+
+	return nil
+}
+
+func (b *Book) GetTaxTables() []*TaxTable {
+	return b.taxTables
+}
+
+func (b *Book) GetTaxTableByName(name string) *TaxTable {
+	return b.taxTablesByGuid[b.taxTablesByName.first(name)]
+}
+
+func (b *Book) GetTaxTableByGUID(guid string) *TaxTable {
+	return b.taxTablesByGuid[guid]
+}
+
+// Adds new billing terms to the book, the GUID is generated if not set
+func (b *Book) AddBillTerm(t *BillTerm) error {
+	if t.Name == "" {
+		return newError(ErrConstraint, "adding billing terms without name")
+	}
+
+	if o := b.GetBillTermByName(t.Name); o != nil && o != t {
+		return newError(ErrConstraint, "billing terms '%s' already exist", t.Name)
+	}
+
+	switch BillTermType(t.Type) {
+	case BillTermTypeDays, BillTermTypeProximo:
+	default:
+		return newError(ErrUnsupported, "billing terms '%s' have type '%s'", t.Name, t.Type)
+	}
+
+	if t.Guid == "" {
+		t.Guid = NewGuid()
+	}
+
+	t.book = b
+	if err := t.write(); err != nil {
+		return err
+	}
+	b.billTerms = append(b.billTerms, t)
+	b.billTermsByGuid[t.Guid] = t
+
 	return nil
 }
 
+func (b *Book) GetBillTerms() []*BillTerm {
+	return b.billTerms
+}
+
+func (b *Book) GetBillTermByName(name string) *BillTerm {
+	return b.billTermsByGuid[b.billTermsByName.first(name)]
+}
+
+func (b *Book) GetBillTermByGUID(guid string) *BillTerm {
+	return b.billTermsByGuid[guid]
+}
+
 // Returns the currency (commodity) of the root account
-func (b *Book) GetDefaultCurrency() *Commodity {
+func (b *Book) GetDefaultCurrency() (*Commodity, error) {
 	if !b.RootAccount.CommodityGuid.Valid {
-		log.Fatal("Root account commodity_guid is null")
+		return nil, newError(ErrCorruptBook, "root account has no commodity")
 	}
 
-	return b.GetCommodityByGUID(b.RootAccount.CommodityGuid.String)
+	c := b.GetCommodityByGUID(b.RootAccount.CommodityGuid.String)
+	if c == nil {
+		return nil, newError(ErrCorruptBook, "commodity %s of root account not found",
+			b.RootAccount.CommodityGuid.String)
+	}
+
+	return c, nil
 }
 
-func (b *Book) Close() {
-	b.DB.Close()
+// Returns the ID following the last one issued by counter (e.g. "gncBill")
+// without incrementing the counter, like qof_book_increment_and_format_counter
+// formats it
+func (b *Book) peekNextId(counter string) (string, error) {
+	format, err := b.getCounterFormat("counter_formats/" + counter)
+	if err != nil {
+		return "", err
+	}
+
+	n, err := b.getCounter("counters/" + counter)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf(format, n+1), nil
+}
+
+// Returns the next ID of counter (e.g. "gncVendor") and increments the counter
+func (b *Book) nextId(counter string) (string, error) {
+	id, err := b.peekNextId(counter)
+	if err != nil {
+		return "", err
+	}
+
+	if _, err := b.incrementCounter("counters/" + counter); err != nil {
+		return "", err
+	}
+
+	return id, nil
+}
+
+// Releases the lock of the book, if taken, and closes its database
+func (b *Book) Close() error {
+	err := b.Unlock()
+	if cerr := b.DB.Close(); err == nil {
+		err = dbError(cerr, "closing book")
+	}
+
+	return err
 }
@@ -0,0 +1,310 @@
+package gnucash
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Creates a book from testdata/schema.sql and the given fixtures in testdata,
+// returns its path
+func createTestBook(tb testing.TB, fixtures ...string) string {
+	path := filepath.Join(tb.TempDir(), "test.gnucash")
+
+	db, err := sqlx.Open("sqlite3", path)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	defer db.Close()
+
+	for _, f := range append([]string{"schema.sql"}, fixtures...) {
+		query, err := os.ReadFile(filepath.Join("testdata", f))
+		if err != nil {
+			tb.Fatal(err)
+		}
+
+		if _, err := db.Exec(string(query)); err != nil {
+			tb.Fatalf("%s: %v", f, err)
+		}
+	}
+
+	return path
+}
+
+// Opens a book created from testdata/schema.sql and the given fixtures, the
+// book is closed when the test ends
+func openTestBook(t *testing.T, fixtures ...string) *Book {
+	book, err := OpenBookFromSQLite(createTestBook(t, fixtures...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if err := book.Close(); err != nil {
+			t.Error(err)
+		}
+	})
+
+	return book
+}
+
+const (
+	benchTransactions = 150000 // with two splits each
+	benchAccounts     = 50
+)
+
+// Creates a book with accounts below an asset account, a bank account and
+// benchTransactions transactions between them, each with a date-posted slot
+func createBenchBook(b *testing.B) string {
+	path := createTestBook(b)
+
+	db, err := sqlx.Open("sqlite3", path)
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer db.Close()
+
+	tx, err := db.Beginx()
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	guid := func(kind string, n int) string {
+		return fmt.Sprintf("%s%0*d", kind, 32-len(kind), n)
+	}
+	exec := func(query string, args ...interface{}) {
+		if _, err := tx.Exec(query, args...); err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	eur, root, assets, bank := guid("eur", 1), guid("root", 1), guid("asst", 1), guid("bank", 1)
+	exec(`INSERT INTO books VALUES (?, ?, ?)`, guid("book", 1), root, guid("tmpl", 1))
+	exec(`INSERT INTO commodities VALUES (?, 'CURRENCY', 'EUR', 'Euro', '978', 100, 1, 'currency', NULL)`, eur)
+	exec(`INSERT INTO accounts VALUES (?, 'Root Account', 'ROOT', ?, 100, 0, NULL, '', '', 0, 0)`, root, eur)
+	exec(`INSERT INTO accounts VALUES (?, 'Assets', 'ASSET', ?, 100, 0, ?, '', '', 0, 1)`, assets, eur, root)
+	exec(`INSERT INTO accounts VALUES (?, 'Bank', 'BANK', ?, 100, 0, ?, '', '', 0, 0)`, bank, eur, assets)
+	for n := 0; n < benchAccounts; n++ {
+		exec(`INSERT INTO accounts VALUES (?, ?, 'EXPENSE', ?, 100, 0, ?, '', '', 0, 0)`,
+			guid("acct", n), fmt.Sprintf("Account %d", n), eur, root)
+	}
+
+	insertTx, err := tx.Preparex(`INSERT INTO transactions VALUES (?, ?, '', ?, ?, ?)`)
+	if err != nil {
+		b.Fatal(err)
+	}
+	insertSplit, err := tx.Preparex(`INSERT INTO splits VALUES (?, ?, ?, '', '', 'n', NULL, ?, 100, ?, 100, NULL)`)
+	if err != nil {
+		b.Fatal(err)
+	}
+	insertSlot, err := tx.Preparex(`INSERT INTO slots (obj_guid, name, slot_type, gdate_val)
+		VALUES (?, 'date-posted', ?, ?)`)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	start := time.Date(2015, 1, 1, 10, 59, 0, 0, time.UTC)
+	for n := 0; n < benchTransactions; n++ {
+		txGuid := guid("tx", n)
+		date := start.Add(time.Duration(n) * time.Hour)
+		posted := date.Format("2006-01-02 15:04:05")
+		value := int64(n%10000 + 1)
+
+		if _, err := insertTx.Exec(txGuid, eur, posted, posted, fmt.Sprintf("Transaction %d", n)); err != nil {
+			b.Fatal(err)
+		}
+		if _, err := insertSplit.Exec(guid("spa", n), txGuid, guid("acct", n%benchAccounts),
+			value, value); err != nil {
+			b.Fatal(err)
+		}
+		if _, err := insertSplit.Exec(guid("spb", n), txGuid, bank, -value, -value); err != nil {
+			b.Fatal(err)
+		}
+		if _, err := insertSlot.Exec(txGuid, SlotTypeGdate, date.Format("20060102")); err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		b.Fatal(err)
+	}
+
+	return path
+}
+
+func BenchmarkOpenBookFromSQLite(b *testing.B) {
+	path := createBenchBook(b)
+
+	benchmarks := []struct {
+		name    string
+		options []OpenOption
+	}{
+		{"all", nil},
+		{"lazily", []OpenOption{LoadLazily()}},
+		{"account", []OpenOption{LoadAccountSubtree("Account 0")}},
+	}
+
+	for _, bm := range benchmarks {
+		b.Run(bm.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				book, err := OpenBookFromSQLite(path, bm.options...)
+				if err != nil {
+					b.Fatal(err)
+				}
+				if err := book.Close(); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// Counters hold the last number issued, which GnuCash increments before
+// formatting the next ID
+func TestCounters(t *testing.T) {
+	book := openTestBook(t, "book.sql")
+
+	id, err := book.GetNextBillId()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id != "00006" {
+		t.Errorf("next bill ID = %s, want 00006", id)
+	}
+	if n, err := book.GetBillCounter(); err != nil || n != 5 {
+		t.Errorf("bill counter = %d (%v) after peeking, want 5", n, err)
+	}
+
+	// Books without a vendor counter start with the first ID
+	for _, want := range []string{"00001", "00002"} {
+		v := &Vendor{DbVendor: DbVendor{Name: "Vendor " + want}}
+		if err := book.AddVendor(v); err != nil {
+			t.Fatal(err)
+		}
+		if v.Id != want {
+			t.Errorf("vendor ID = %s, want %s", v.Id, want)
+		}
+	}
+	if n, err := book.GetVendorCounter(); err != nil || n != 2 {
+		t.Errorf("vendor counter = %d (%v), want 2", n, err)
+	}
+}
+
+func TestAddCustomerDuplicateName(t *testing.T) {
+	book := openTestBook(t, "book.sql")
+
+	if err := book.AddCustomer(&Customer{DbCustomer: DbCustomer{Name: "ACME"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{"ACME", ""} {
+		err := book.AddCustomer(&Customer{DbCustomer: DbCustomer{Name: name}})
+		if !errors.Is(err, ErrConstraint) {
+			t.Errorf("adding customer '%s': error %v, want %v", name, err, ErrConstraint)
+		}
+	}
+	if n := len(book.GetCustomers()); n != 1 {
+		t.Errorf("%d customers, want 1", n)
+	}
+}
+
+func TestAddVendorDuplicateName(t *testing.T) {
+	book := openTestBook(t, "book.sql")
+
+	if err := book.AddVendor(&Vendor{DbVendor: DbVendor{Name: "SWM"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{"SWM", ""} {
+		err := book.AddVendor(&Vendor{DbVendor: DbVendor{Name: name}})
+		if !errors.Is(err, ErrConstraint) {
+			t.Errorf("adding vendor '%s': error %v, want %v", name, err, ErrConstraint)
+		}
+	}
+	if n := len(book.GetVendors()); n != 1 {
+		t.Errorf("%d vendors, want 1", n)
+	}
+}
+
+func TestOwnerIndexes(t *testing.T) {
+	book := openTestBook(t, "book.sql")
+
+	v1 := &Vendor{DbVendor: DbVendor{Name: "SWM"}}
+	v2 := &Vendor{DbVendor: DbVendor{Name: "Telekom"}}
+	c := &Customer{DbCustomer: DbCustomer{Name: "ACME"}}
+	e := &Employee{DbEmployee: DbEmployee{Username: "chris"}}
+	for _, err := range []error{book.AddVendor(v1), book.AddVendor(v2), book.AddCustomer(c),
+		book.AddEmployee(e)} {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Renames are written, the old names no longer found
+	v1.Name = "Stadtwerke"
+	v2.Name = "SWM"
+	c.Name = "ACME Corp."
+	e.Username = "cmeier"
+	for _, err := range []error{v1.Write(), v2.Write(), c.Write(), e.Write()} {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if o := book.GetVendorByName("Stadtwerke"); o != v1 {
+		t.Errorf("vendor Stadtwerke = %v, want %s", o, v1.Guid)
+	}
+	if o := book.GetVendorByName("SWM"); o != v2 {
+		t.Errorf("vendor SWM = %v, want %s", o, v2.Guid)
+	}
+	if o := book.GetVendorByName("Telekom"); o != nil {
+		t.Errorf("vendor Telekom = %s, want none", o.Guid)
+	}
+	if o := book.GetCustomerByName("ACME"); o != nil {
+		t.Errorf("customer ACME = %s, want none", o.Guid)
+	}
+	if o := book.GetCustomerByName("ACME Corp."); o != c {
+		t.Errorf("customer ACME Corp. = %v, want %s", o, c.Guid)
+	}
+	if o := book.GetEmployeeByUsername("chris"); o != nil {
+		t.Errorf("employee chris = %s, want none", o.Guid)
+	}
+	if o := book.GetEmployeeByUsername("cmeier"); o != e {
+		t.Errorf("employee cmeier = %v, want %s", o, e.Guid)
+	}
+
+	// Duplicate names (only possible by renaming) find the first indexed
+	v2.Name = "Stadtwerke"
+	if err := v2.Write(); err != nil {
+		t.Fatal(err)
+	}
+	if o := book.GetVendorByName("Stadtwerke"); o != v1 {
+		t.Errorf("vendor Stadtwerke = %v, want %s", o, v1.Guid)
+	}
+	if err := book.AddVendor(&Vendor{DbVendor: DbVendor{Name: "SWM"}}); err != nil {
+		t.Errorf("adding vendor SWM: %v", err)
+	}
+
+	// IDs, changed IDs
+	c.Id = "C-100"
+	if err := c.Write(); err != nil {
+		t.Fatal(err)
+	}
+	if o := book.GetVendorByID(v2.Id); o != v2 {
+		t.Errorf("vendor %s = %v, want %s", v2.Id, o, v2.Guid)
+	}
+	if o := book.GetCustomerByID("C-100"); o != c {
+		t.Errorf("customer C-100 = %v, want %s", o, c.Guid)
+	}
+	if o := book.GetEmployeeByID(e.Id); o != e {
+		t.Errorf("employee %s = %v, want %s", e.Id, o, e.Guid)
+	}
+	if o := book.GetCustomerByID("nonexistent"); o != nil {
+		t.Errorf("customer nonexistent = %s, want none", o.Guid)
+	}
+}
@@ -0,0 +1,181 @@
+package gnucash
+
+import (
+	"database/sql"
+	"math/big"
+)
+
+type Customer struct {
+	book *Book
+	DbCustomer
+
+	indexed indexedKeys // keys of the book's indexes, see index
+}
+
+type DbCustomer struct {
+	Guid          string
+	Name          string
+	Id            string
+	Notes         string
+	Active        int
+	DiscountNum   int64 `db:"discount_num"`
+	DiscountDenom int64 `db:"discount_denom"`
+	CreditNum     int64 `db:"credit_num"`
+	CreditDenom   int64 `db:"credit_denom"`
+	Currency      string
+	TaxOverride   int            `db:"tax_override"`
+	AddrName      sql.NullString `db:"addr_name"`
+	AddrAddr1     sql.NullString `db:"addr_addr1"`
+	AddrAddr2     sql.NullString `db:"addr_addr2"`
+	AddrAddr3     sql.NullString `db:"addr_addr3"`
+	AddrAddr4     sql.NullString `db:"addr_addr4"`
+	AddrPhone     sql.NullString `db:"addr_phone"`
+	AddrFax       sql.NullString `db:"addr_fax"`
+	AddrEmail     sql.NullString `db:"addr_email"`
+	ShipaddrName  sql.NullString `db:"shipaddr_name"`
+	ShipaddrAddr1 sql.NullString `db:"shipaddr_addr1"`
+	ShipaddrAddr2 sql.NullString `db:"shipaddr_addr2"`
+	ShipaddrAddr3 sql.NullString `db:"shipaddr_addr3"`
+	ShipaddrAddr4 sql.NullString `db:"shipaddr_addr4"`
+	ShipaddrPhone sql.NullString `db:"shipaddr_phone"`
+	ShipaddrFax   sql.NullString `db:"shipaddr_fax"`
+	ShipaddrEmail sql.NullString `db:"shipaddr_email"`
+	Terms         sql.NullString
+	TaxIncluded   sql.NullInt32 `db:"tax_included"`
+	Taxtable      sql.NullString
+}
+
+// Postal address (and contact details) of a business entity
+type Address struct {
+	Name  string
+	Addr1 string
+	Addr2 string
+	Addr3 string
+	Addr4 string
+	Phone string
+	Fax   string
+	Email string
+}
+
+func (c *Customer) Write() error {
+	query := `INSERT OR REPLACE INTO "customers" ("guid", "name", "id", "notes",
+			"active", "discount_num", "discount_denom", "credit_num",
+			"credit_denom", "currency", "tax_override", "addr_name", "addr_addr1",
+			"addr_addr2", "addr_addr3", "addr_addr4", "addr_phone", "addr_fax",
+			"addr_email", "shipaddr_name", "shipaddr_addr1", "shipaddr_addr2",
+			"shipaddr_addr3", "shipaddr_addr4", "shipaddr_phone", "shipaddr_fax",
+			"shipaddr_email", "terms", "tax_included", "taxtable")
+		VALUES (:guid, :name, :id, :notes, :active, :discount_num,
+			:discount_denom, :credit_num, :credit_denom, :currency, :tax_override,
+			:addr_name, :addr_addr1, :addr_addr2, :addr_addr3, :addr_addr4,
+			:addr_phone, :addr_fax, :addr_email, :shipaddr_name, :shipaddr_addr1,
+			:shipaddr_addr2, :shipaddr_addr3, :shipaddr_addr4, :shipaddr_phone,
+			:shipaddr_fax, :shipaddr_email, :terms, :tax_included, :taxtable)`
+
+	_, err := c.book.DB.NamedExec(query, c.DbCustomer)
+	if err != nil {
+		return dbError(err, "writing customer %s", c.Name)
+	}
+
+	c.index()
+	return nil
+}
+
+// Updates the book's indexes by name and ID
+func (c *Customer) index() {
+	c.book.customersByName.update(c.Guid, c.indexed.ok, c.indexed.name, c.Name)
+	c.book.customersById.update(c.Guid, c.indexed.ok, c.indexed.id, c.Id)
+	c.indexed = indexedKeys{true, c.Name, c.Id}
+}
+
+// Returns the billing address of the customer
+func (c *Customer) GetAddress() Address {
+	return Address{c.AddrName.String, c.AddrAddr1.String, c.AddrAddr2.String,
+		c.AddrAddr3.String, c.AddrAddr4.String, c.AddrPhone.String,
+		c.AddrFax.String, c.AddrEmail.String}
+}
+
+// Sets the billing address of the customer (call Write to save it)
+func (c *Customer) SetAddress(a Address) {
+	c.AddrName = sql.NullString{a.Name, true}
+	c.AddrAddr1 = sql.NullString{a.Addr1, true}
+	c.AddrAddr2 = sql.NullString{a.Addr2, true}
+	c.AddrAddr3 = sql.NullString{a.Addr3, true}
+	c.AddrAddr4 = sql.NullString{a.Addr4, true}
+	c.AddrPhone = sql.NullString{a.Phone, true}
+	c.AddrFax = sql.NullString{a.Fax, true}
+	c.AddrEmail = sql.NullString{a.Email, true}
+}
+
+// Returns the shipping address of the customer
+func (c *Customer) GetShipAddress() Address {
+	return Address{c.ShipaddrName.String, c.ShipaddrAddr1.String,
+		c.ShipaddrAddr2.String, c.ShipaddrAddr3.String, c.ShipaddrAddr4.String,
+		c.ShipaddrPhone.String, c.ShipaddrFax.String, c.ShipaddrEmail.String}
+}
+
+// Sets the shipping address of the customer (call Write to save it)
+func (c *Customer) SetShipAddress(a Address) {
+	c.ShipaddrName = sql.NullString{a.Name, true}
+	c.ShipaddrAddr1 = sql.NullString{a.Addr1, true}
+	c.ShipaddrAddr2 = sql.NullString{a.Addr2, true}
+	c.ShipaddrAddr3 = sql.NullString{a.Addr3, true}
+	c.ShipaddrAddr4 = sql.NullString{a.Addr4, true}
+	c.ShipaddrPhone = sql.NullString{a.Phone, true}
+	c.ShipaddrFax = sql.NullString{a.Fax, true}
+	c.ShipaddrEmail = sql.NullString{a.Email, true}
+}
+
+// Returns the default discount (in percent) of the customer
+func (c *Customer) GetDiscount() *big.Rat {
+	return big.NewRat(c.DiscountNum, c.DiscountDenom)
+}
+
+// Sets the default discount (in percent) of the customer (call Write to save
+// it)
+func (c *Customer) SetDiscount(d *big.Rat) {
+	c.DiscountNum = d.Num().Int64()
+	c.DiscountDenom = d.Denom().Int64()
+}
+
+// Returns the credit limit of the customer
+func (c *Customer) GetCreditLimit() *big.Rat {
+	return big.NewRat(c.CreditNum, c.CreditDenom)
+}
+
+// Sets the credit limit of the customer (call Write to save it)
+func (c *Customer) SetCreditLimit(l *big.Rat) {
+	c.CreditNum = l.Num().Int64()
+	c.CreditDenom = l.Denom().Int64()
+}
+
+// Sets the default billing terms (GUID, "" for none) of the customer (call
+// Write to save them)
+func (c *Customer) SetTermsGuid(guid string) {
+	c.Terms = sql.NullString{guid, guid != ""}
+}
+
+// Returns the default billing terms of the customer, nil if it has none
+func (c *Customer) GetTerms() *BillTerm {
+	if !c.Terms.Valid {
+		return nil
+	}
+
+	return c.book.GetBillTermByGUID(c.Terms.String)
+}
+
+// Sets the default tax table (GUID, "" for none) of the customer and whether
+// prices include tax (call Write to save them)
+func (c *Customer) SetTaxTableGuid(guid string, included bool) {
+	c.Taxtable = sql.NullString{guid, guid != ""}
+	c.TaxOverride = 0
+	if guid != "" {
+		c.TaxOverride = 1
+	}
+
+	// GnuCash uses 1 for 'yes', 2 for 'no' and 3 for 'use global'
+	c.TaxIncluded = sql.NullInt32{2, true}
+	if included {
+		c.TaxIncluded = sql.NullInt32{1, true}
+	}
+}
@@ -2,12 +2,14 @@ package gnucash
 
 import (
 	"database/sql"
-	"log"
+	"math/big"
 )
 
 type Employee struct {
 	book *Book
 	DbEmployee
+
+	indexed indexedKeys // keys of the book's indexes, see index
 }
 
 type DbEmployee struct {
@@ -33,7 +35,7 @@ type DbEmployee struct {
 	AddrEmail    sql.NullString `db:"addr_email"`
 }
 
-func (e *Employee) write() {
+func (e *Employee) Write() error {
 	query := `INSERT OR REPLACE INTO "employees" ("guid", "username", "id",
 			"language", "acl", "active", "currency", "ccard_guid",
 			"workday_num", "workday_denom", "rate_num", "rate_denom",
@@ -44,8 +46,82 @@ func (e *Employee) write() {
 			:addr_name, :addr_addr1, :addr_addr2, :addr_addr3, :addr_addr4,
 			:addr_phone, :addr_fax, :addr_email)`
 
-	_, err := e.book.DB.NamedExec(query, e)
+	_, err := e.book.DB.NamedExec(query, e.DbEmployee)
 	if err != nil {
-		log.Fatal(err)
+		return dbError(err, "writing employee %s", e.Username)
+	}
+
+	e.index()
+	return nil
+}
+
+// Updates the book's indexes by username and ID
+func (e *Employee) index() {
+	e.book.employeesByName.update(e.Guid, e.indexed.ok, e.indexed.name, e.Username)
+	e.book.employeesById.update(e.Guid, e.indexed.ok, e.indexed.id, e.Id)
+	e.indexed = indexedKeys{true, e.Username, e.Id}
+}
+
+// Returns the address (including name and email) of the employee
+func (e *Employee) GetAddress() Address {
+	return Address{e.AddrName.String, e.AddrAddr1.String, e.AddrAddr2.String,
+		e.AddrAddr3.String, e.AddrAddr4.String, e.AddrPhone.String,
+		e.AddrFax.String, e.AddrEmail.String}
+}
+
+// Sets the address (including name and email) of the employee (call Write to
+// save it)
+func (e *Employee) SetAddress(a Address) {
+	e.AddrName = sql.NullString{a.Name, true}
+	e.AddrAddr1 = sql.NullString{a.Addr1, true}
+	e.AddrAddr2 = sql.NullString{a.Addr2, true}
+	e.AddrAddr3 = sql.NullString{a.Addr3, true}
+	e.AddrAddr4 = sql.NullString{a.Addr4, true}
+	e.AddrPhone = sql.NullString{a.Phone, true}
+	e.AddrFax = sql.NullString{a.Fax, true}
+	e.AddrEmail = sql.NullString{a.Email, true}
+}
+
+// Returns the credit card account expenses paid by card are booked against,
+// nil if the employee has none
+func (e *Employee) GetCCardAccount() *Account {
+	if !e.CCardGuid.Valid {
+		return nil
 	}
+
+	return e.book.GetAccountByGUID(e.CCardGuid.String)
+}
+
+// Sets the credit card account of the employee, nil for none (call Write to
+// save it)
+func (e *Employee) SetCCardAccount(a *Account) {
+	e.CCardGuid = sql.NullString{}
+	if a != nil {
+		e.CCardGuid = sql.NullString{a.Guid, true}
+	}
+}
+
+// Sets the hourly rate of the employee (call Write to save it)
+func (e *Employee) SetRate(r *big.Rat) {
+	e.RateNum = sql.NullInt64{r.Num().Int64(), true}
+	e.RateDenom = sql.NullInt64{r.Denom().Int64(), true}
+}
+
+// Sets the hours per workday of the employee (call Write to save them)
+func (e *Employee) SetWorkday(w *big.Rat) {
+	e.WorkdayNum = sql.NullInt64{w.Num().Int64(), true}
+	e.WorkdayDenom = sql.NullInt64{w.Denom().Int64(), true}
+}
+
+// Marks the employee as inactive and saves it. Inactive employees keep their
+// vouchers but can't be given new shares.
+func (e *Employee) Deactivate() error {
+	e.Active = 0
+	return e.Write()
+}
+
+// Marks the employee as active and saves it
+func (e *Employee) Activate() error {
+	e.Active = 1
+	return e.Write()
 }
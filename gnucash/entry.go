@@ -2,7 +2,6 @@ package gnucash
 
 import (
 	"database/sql"
-	"log"
 	"math/big"
 )
 
@@ -61,7 +60,17 @@ func (e *Entry) GetBill() *Invoice {
 	return e.book.GetInvoiceByGUID(e.Bill.String)
 }
 
-func (e *Entry) Write() {
+// Returns how the entry of an expense voucher was paid, entries without payment
+// type count as paid in cash
+func (e *Entry) GetPaymentType() EntryPaymentType {
+	if !e.BPaytype.Valid {
+		return EntryPaymentTypeCash
+	}
+
+	return EntryPaymentType(e.BPaytype.Int32)
+}
+
+func (e *Entry) Write() error {
 	query := `INSERT INTO "entries" ("guid", "date", "date_entered",
 			"description", "action", "notes", "quantity_num", "quantity_denom",
 			"i_acct", "i_price_num", "i_price_denom", "i_discount_num",
@@ -77,24 +86,300 @@ func (e *Entry) Write() {
 			:b_taxincluded, :b_taxtable, :b_paytype, :billable, :billto_type,
 			:billto_guid, :order_guid)`
 	_, err := e.book.DB.NamedExec(query, e.DbEntry)
-	if err != nil {
-		log.Fatal(err)
-	}
+	return dbError(err, "writing entry %s", e.Guid)
 }
 
-// WARNING: Does not support discounts!
-func (e *Entry) GetSubtotal() *big.Rat {
+// Returns quantity times price, using the bill price for bills and vouchers and
+// the invoice price for customer invoices
+func (e *Entry) getAggregate() (*big.Rat, error) {
 	quantity := big.NewRat(0, 1)
 	if e.QuantityNum.Valid && e.QuantityDenom.Valid {
 		quantity = big.NewRat(e.QuantityNum.Int64, e.QuantityDenom.Int64)
 	}
 
 	price := big.NewRat(0, 1)
-	if e.BPriceNum.Valid && e.BPriceDenom.Valid {
+	if e.Bill.Valid && e.BPriceNum.Valid && e.BPriceDenom.Valid {
 		price = big.NewRat(e.BPriceNum.Int64, e.BPriceDenom.Int64)
+	} else if e.Invoice.Valid && e.IPriceNum.Valid && e.IPriceDenom.Valid {
+		price = big.NewRat(e.IPriceNum.Int64, e.IPriceDenom.Int64)
 	} else {
-		log.Fatal("GetSubtotal only supported for vendor bills")
+		return nil, newError(ErrCorruptBook, "entry %s has no price for its bill or invoice", e.Guid)
+	}
+
+	return quantity.Mul(quantity, price), nil
+}
+
+// Returns the net value, the taxes and the gross value of the entry, computed
+// (and rounded to the currency of the entry's document) as GnuCash does
+func (e *Entry) GetSubtotal() (net *big.Rat, tax *big.Rat, gross *big.Rat, err error) {
+	net, taxes, _, err := e.computeValues()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	tax = new(big.Rat)
+	for _, t := range taxes {
+		tax.Add(tax, t.Amount)
+	}
+
+	return net, tax, new(big.Rat).Add(net, tax), nil
+}
+
+// Returns the net value of the entry and the taxes on it per tax account
+func (e *Entry) GetValues() (*big.Rat, []Tax, error) {
+	net, taxes, _, err := e.computeValues()
+	return net, taxes, err
+}
+
+// Returns the value of the entry excluding taxes
+func (e *Entry) GetNet() (*big.Rat, error) {
+	net, _, _, err := e.GetSubtotal()
+	return net, err
+}
+
+// Returns the sum of all taxes on the entry
+func (e *Entry) GetTax() (*big.Rat, error) {
+	_, tax, _, err := e.GetSubtotal()
+	return tax, err
+}
+
+// Returns the value of the entry including taxes
+func (e *Entry) GetGross() (*big.Rat, error) {
+	_, _, gross, err := e.GetSubtotal()
+	return gross, err
+}
+
+// Returns the discount granted on the entry
+func (e *Entry) GetDiscountValue() (*big.Rat, error) {
+	_, _, discount, err := e.computeValues()
+	return discount, err
+}
+
+// Computes the value of the entry like gncEntryComputeValue does:
+//
+//  1. aggregate = quantity * price
+//  2. pretax = aggregate, or (aggregate - fixed taxes) / (1 + tax percentage)
+//     if the price includes taxes
+//  3. the discount (invoice side only) is a value or a percentage of pretax
+//     (pretax and sametime discounts) or of pretax plus taxes (posttax
+//     discounts); net = pretax - discount
+//  4. percentage taxes are computed on net for pretax discounts, on pretax
+//     otherwise
+//
+// Net value, discount and each tax are rounded to the currency.
+func (e *Entry) computeValues() (net *big.Rat, taxes []Tax, discount *big.Rat, err error) {
+	aggregate, err := e.getAggregate()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	denom := e.getCurrencyDenom()
+
+	// Fixed amounts (tax values and value discounts) follow the sign of the
+	// aggregate, which is negative for credit notes
+	signed := func(r *big.Rat) *big.Rat {
+		if aggregate.Sign() < 0 {
+			return r.Neg(r)
+		}
+		return r
+	}
+
+	percent, value := new(big.Rat), new(big.Rat)
+	t, err := e.GetTaxTable()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if t != nil {
+		percent, value, err = t.getRates()
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		percent.Mul(percent, big.NewRat(1, 100))
+		signed(value)
+	}
+
+	pretax := new(big.Rat).Set(aggregate)
+	if t != nil && e.IsTaxIncluded() {
+		pretax.Sub(pretax, value)
+		pretax.Quo(pretax, new(big.Rat).Add(big.NewRat(1, 1), percent))
+	}
+
+	discount = new(big.Rat)
+	how := DiscountHowPretax
+	if e.Invoice.Valid && e.IDiscountNum.Valid && e.IDiscountDenom.Valid &&
+		e.IDiscountDenom.Int64 != 0 {
+		if e.IDiscHow.Valid {
+			how = DiscountHow(e.IDiscHow.String)
+		}
+
+		amount := big.NewRat(e.IDiscountNum.Int64, e.IDiscountDenom.Int64)
+		switch e.IDiscType.String {
+		case AmountTypeValue.String():
+			discount = signed(amount)
+		case AmountTypePercent.String(), "":
+			base := new(big.Rat).Set(pretax)
+			if how == DiscountHowPosttax {
+				// Discount on pretax plus taxes
+				base.Add(base, new(big.Rat).Mul(pretax, percent))
+				base.Add(base, value)
+			}
+			discount = base.Mul(base, amount)
+			discount.Mul(discount, big.NewRat(1, 100))
+		default:
+			return nil, nil, nil, newError(ErrUnsupported, "entry %s has discount type '%s'",
+				e.Guid, e.IDiscType.String)
+		}
+
+		switch how {
+		case DiscountHowPretax, DiscountHowSametime, DiscountHowPosttax:
+		default:
+			return nil, nil, nil, newError(ErrUnsupported, "entry %s has discount application '%s'",
+				e.Guid, how)
+		}
+	}
+
+	net = new(big.Rat).Sub(pretax, discount)
+
+	taxes = []Tax{}
+	if t != nil {
+		base := pretax
+		if how == DiscountHowPretax {
+			base = net
+		}
+
+		for _, te := range t.Entries {
+			amount := te.GetAmount()
+			if AmountType(te.Type) == AmountTypePercent {
+				amount.Mul(amount, base)
+				amount.Mul(amount, big.NewRat(1, 100))
+			} else {
+				signed(amount)
+			}
+
+			taxes = append(taxes, Tax{te.Account, roundRat(amount, denom)})
+		}
+	}
+
+	return roundRat(net, denom), taxes, roundRat(discount, denom), nil
+}
+
+// Sets the discount of the entry of a customer invoice (nil for none), a value
+// or a percentage (e.g. 10 for 10%) applied as given by how (call before adding
+// the entry to its invoice)
+func (e *Entry) SetInvoiceDiscount(amount *big.Rat, tp AmountType, how DiscountHow) {
+	if amount == nil {
+		e.IDiscountNum = sql.NullInt64{0, true}
+		e.IDiscountDenom = sql.NullInt64{1, true}
+		return
+	}
+
+	e.IDiscountNum = sql.NullInt64{amount.Num().Int64(), true}
+	e.IDiscountDenom = sql.NullInt64{amount.Denom().Int64(), true}
+	e.IDiscType = sql.NullString{tp.String(), true}
+	e.IDiscHow = sql.NullString{string(how), true}
+}
+
+// Sets the tax table of the entry of a bill or voucher, nil for none (call
+// before adding the entry to its bill). If included is true, the bill price
+// includes the taxes.
+func (e *Entry) SetBillTaxTable(t *TaxTable, included bool) {
+	e.BTaxable = sql.NullInt32{0, true}
+	e.BTaxincluded = sql.NullInt32{boolToInt32(included), true}
+	e.BTaxtable = sql.NullString{}
+	if t != nil {
+		e.BTaxable = sql.NullInt32{1, true}
+		e.BTaxtable = sql.NullString{t.Guid, true}
+	}
+}
+
+// Sets the tax table of the entry of a customer invoice, nil for none (call
+// before adding the entry to its invoice). If included is true, the invoice
+// price includes the taxes.
+func (e *Entry) SetInvoiceTaxTable(t *TaxTable, included bool) {
+	e.ITaxable = sql.NullInt32{0, true}
+	e.ITaxincluded = sql.NullInt32{boolToInt32(included), true}
+	e.ITaxtable = sql.NullString{}
+	if t != nil {
+		e.ITaxable = sql.NullInt32{1, true}
+		e.ITaxtable = sql.NullString{t.Guid, true}
+	}
+}
+
+// Returns the tax table applying to the entry (bill or invoice side, depending
+// on the document it belongs to), nil if the entry is not taxable
+func (e *Entry) GetTaxTable() (*TaxTable, error) {
+	taxable, table := e.ITaxable, e.ITaxtable
+	if e.Bill.Valid {
+		taxable, table = e.BTaxable, e.BTaxtable
+	}
+
+	if !taxable.Valid || taxable.Int32 == 0 || !table.Valid {
+		return nil, nil
+	}
+
+	t := e.book.GetTaxTableByGUID(table.String)
+	if t == nil {
+		return nil, newError(ErrCorruptBook, "tax table %s of entry %s not found", table.String, e.Guid)
+	}
+
+	return t, nil
+}
+
+// Returns true if the price of the entry includes its taxes
+func (e *Entry) IsTaxIncluded() bool {
+	if e.Bill.Valid {
+		return e.BTaxincluded.Valid && e.BTaxincluded.Int32 != 0
+	}
+
+	return e.ITaxincluded.Valid && e.ITaxincluded.Int32 != 0
+}
+
+// Returns the smallest fraction of the currency of the entry's document
+// (defaults to cents)
+func (e *Entry) getCurrencyDenom() int64 {
+	i := e.GetBill()
+	if i == nil {
+		i = e.GetInvoice()
+	}
+
+	if i != nil {
+		if c := e.book.GetCommodityByGUID(i.Currency); c != nil && c.Fraction > 0 {
+			return int64(c.Fraction)
+		}
+	}
+
+	return 100
+}
+
+// Deletes entry and removes it from its invoice
+func (e *Entry) Delete() error {
+	if err := e.book.checkComplete("deleting entry " + e.Guid); err != nil {
+		return err
+	}
+
+	_, err := e.book.DB.Exec(`DELETE FROM "entries" WHERE "guid"=?`, e.Guid)
+	if err != nil {
+		return dbError(err, "deleting entry %s", e.Guid)
+	}
+
+	for _, i := range []*Invoice{e.GetInvoice(), e.GetBill()} {
+		if i == nil {
+			continue
+		}
+
+		for n, x := range i.Entries {
+			if x == e {
+				i.Entries = append(i.Entries[:n], i.Entries[n+1:]...)
+				break
+			}
+		}
+	}
+
+	for n, x := range e.book.entries {
+		if x == e {
+			e.book.entries = append(e.book.entries[:n], e.book.entries[n+1:]...)
+			break
+		}
 	}
 
-	return quantity.Mul(quantity, price)
+	return nil
 }
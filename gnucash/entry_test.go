@@ -0,0 +1,395 @@
+package gnucash
+
+import (
+	"database/sql"
+	"math/big"
+	"testing"
+)
+
+// Tax of a tax table of entryTestCase, a percentage or a fixed value
+type testTax struct {
+	amount string
+	tp     AmountType
+}
+
+type entryTestCase struct {
+	name     string
+	bill     bool   // entry of a bill instead of a customer invoice
+	fraction int    // smallest fraction of the currency
+	quantity string // negative for credit notes
+	price    string
+	taxes    []testTax
+	included bool // price includes taxes
+	discount string
+	discType AmountType
+	discHow  DiscountHow
+
+	net          string
+	tax          []string // per entry of the tax table
+	wantDiscount string
+}
+
+// Expected values follow gncEntryComputeValue, rounding half away from zero to
+// the currency like GnuCash does. TestEntryValuesOfPostedDocuments checks entries
+// against the post transactions of their documents instead.
+var entryTestCases = []entryTestCase{
+	{
+		name: "no tax table", quantity: "3", price: "0.333",
+		net: "1.00", tax: []string{}, wantDiscount: "0",
+	},
+	{
+		name: "percent discount pretax", quantity: "2", price: "24.99",
+		taxes:    []testTax{{"19", AmountTypePercent}},
+		discount: "10", discType: AmountTypePercent, discHow: DiscountHowPretax,
+		net: "44.98", tax: []string{"8.55"}, wantDiscount: "5.00",
+	},
+	{
+		name: "percent discount pretax on 100", quantity: "1", price: "100",
+		taxes:    []testTax{{"19", AmountTypePercent}},
+		discount: "10", discType: AmountTypePercent, discHow: DiscountHowPretax,
+		net: "90", tax: []string{"17.10"}, wantDiscount: "10",
+	},
+	{
+		name: "percent discount sametime", quantity: "1", price: "100",
+		taxes:    []testTax{{"19", AmountTypePercent}},
+		discount: "10", discType: AmountTypePercent, discHow: DiscountHowSametime,
+		net: "90", tax: []string{"19"}, wantDiscount: "10",
+	},
+	{
+		name: "percent discount posttax", quantity: "1", price: "100",
+		taxes:    []testTax{{"19", AmountTypePercent}},
+		discount: "10", discType: AmountTypePercent, discHow: DiscountHowPosttax,
+		net: "88.10", tax: []string{"19"}, wantDiscount: "11.90",
+	},
+	{
+		name: "percent discount posttax with fixed tax", quantity: "1", price: "100",
+		taxes:    []testTax{{"19", AmountTypePercent}, {"1", AmountTypeValue}},
+		discount: "10", discType: AmountTypePercent, discHow: DiscountHowPosttax,
+		net: "88.00", tax: []string{"19", "1"}, wantDiscount: "12.00",
+	},
+	{
+		name: "value discount pretax", quantity: "1", price: "100",
+		taxes:    []testTax{{"19", AmountTypePercent}},
+		discount: "5", discType: AmountTypeValue, discHow: DiscountHowPretax,
+		net: "95", tax: []string{"18.05"}, wantDiscount: "5",
+	},
+	{
+		name: "value discount sametime", quantity: "1", price: "100",
+		taxes:    []testTax{{"19", AmountTypePercent}},
+		discount: "5", discType: AmountTypeValue, discHow: DiscountHowSametime,
+		net: "95", tax: []string{"19"}, wantDiscount: "5",
+	},
+	{
+		name: "value discount posttax", quantity: "1", price: "100",
+		taxes:    []testTax{{"19", AmountTypePercent}},
+		discount: "5", discType: AmountTypeValue, discHow: DiscountHowPosttax,
+		net: "95", tax: []string{"19"}, wantDiscount: "5",
+	},
+	{
+		name: "tax included", quantity: "1", price: "119",
+		taxes: []testTax{{"19", AmountTypePercent}}, included: true,
+		net: "100", tax: []string{"19"}, wantDiscount: "0",
+	},
+	{
+		name: "tax included percent and value", quantity: "1", price: "121",
+		taxes:    []testTax{{"19", AmountTypePercent}, {"2", AmountTypeValue}},
+		included: true,
+		net:      "100", tax: []string{"19", "2"}, wantDiscount: "0",
+	},
+	{
+		name: "tax included percent and value rounded", quantity: "1", price: "10.00",
+		taxes:    []testTax{{"19", AmountTypePercent}, {"0.50", AmountTypeValue}},
+		included: true,
+		net:      "7.98", tax: []string{"1.52", "0.50"}, wantDiscount: "0",
+	},
+	{
+		// Net and taxes are rounded each, so they add up to 10.01 instead
+		// of the price of 10.00. GnuCash books the rounded amounts as well
+		// instead of moving the difference to one of them.
+		name: "tax included rounding difference", quantity: "1", price: "10.00",
+		taxes:    []testTax{{"19", AmountTypePercent}, {"7", AmountTypePercent}},
+		included: true,
+		net:      "7.94", tax: []string{"1.51", "0.56"}, wantDiscount: "0",
+	},
+	{
+		name: "tax included with percent discount", quantity: "1", price: "119",
+		taxes: []testTax{{"19", AmountTypePercent}}, included: true,
+		discount: "10", discType: AmountTypePercent, discHow: DiscountHowPretax,
+		net: "90", tax: []string{"17.10"}, wantDiscount: "10",
+	},
+	{
+		name: "credit note", quantity: "-2", price: "24.99",
+		taxes:    []testTax{{"19", AmountTypePercent}},
+		discount: "10", discType: AmountTypePercent, discHow: DiscountHowPretax,
+		net: "-44.98", tax: []string{"-8.55"}, wantDiscount: "-5.00",
+	},
+	{
+		name: "credit note tax included", quantity: "-1", price: "121",
+		taxes:    []testTax{{"19", AmountTypePercent}, {"2", AmountTypeValue}},
+		included: true,
+		net:      "-100", tax: []string{"-19", "-2"}, wantDiscount: "0",
+	},
+	{
+		name: "credit note rounds half away from zero", quantity: "-1", price: "0.125",
+		net: "-0.13", tax: []string{}, wantDiscount: "0",
+	},
+	{
+		name: "currency without fraction", fraction: 1, quantity: "1", price: "1234",
+		taxes: []testTax{{"8", AmountTypePercent}},
+		net:   "1234", tax: []string{"99"}, wantDiscount: "0",
+	},
+	{
+		name: "currency without fraction discount", fraction: 1, quantity: "3", price: "333",
+		taxes:    []testTax{{"10", AmountTypePercent}},
+		discount: "5", discType: AmountTypePercent, discHow: DiscountHowPretax,
+		net: "949", tax: []string{"95"}, wantDiscount: "50",
+	},
+	{
+		name: "currency with thousandths", fraction: 1000, quantity: "1", price: "12.3456",
+		taxes: []testTax{{"5", AmountTypePercent}},
+		net:   "12.346", tax: []string{"0.617"}, wantDiscount: "0",
+	},
+	{
+		name: "bill", bill: true, quantity: "2", price: "59.50",
+		taxes: []testTax{{"19", AmountTypePercent}}, included: true,
+		net: "100", tax: []string{"19"}, wantDiscount: "0",
+	},
+	{
+		// Discounts only apply to customer invoices
+		name: "bill ignores discount", bill: true, quantity: "1", price: "100",
+		taxes:    []testTax{{"19", AmountTypePercent}},
+		discount: "10", discType: AmountTypePercent, discHow: DiscountHowPretax,
+		net: "100", tax: []string{"19"}, wantDiscount: "0",
+	},
+}
+
+func testRat(t *testing.T, s string) *big.Rat {
+	r, ok := new(big.Rat).SetString(s)
+	if !ok {
+		t.Fatalf("invalid number %q", s)
+	}
+	return r
+}
+
+// Returns a book (without database) holding the entry of tc, its invoice or
+// bill, the tax table and the currency
+func newTestEntry(t *testing.T, tc entryTestCase) *Entry {
+	b := &Book{
+		commoditiesByGuid: make(map[string]*Commodity),
+		taxTablesByGuid:   make(map[string]*TaxTable),
+		invoicesByGuid:    make(map[string]*Invoice),
+	}
+
+	fraction := tc.fraction
+	if fraction == 0 {
+		fraction = 100
+	}
+	b.commoditiesByGuid["currency"] = &Commodity{DbCommodity{Guid: "currency", Fraction: fraction}}
+
+	i := &Invoice{book: b, DbInvoice: DbInvoice{Guid: "invoice", Currency: "currency"}}
+	b.invoicesByGuid[i.Guid] = i
+
+	e := &Entry{book: b}
+	e.Guid = "entry"
+	quantity := testRat(t, tc.quantity)
+	e.QuantityNum = sql.NullInt64{quantity.Num().Int64(), true}
+	e.QuantityDenom = sql.NullInt64{quantity.Denom().Int64(), true}
+
+	var table *TaxTable
+	if tc.taxes != nil {
+		table = &TaxTable{book: b, DbTaxTable: DbTaxTable{Guid: "taxtable", Name: "VAT"}}
+		for n, tax := range tc.taxes {
+			amount := testRat(t, tax.amount)
+			table.Entries = append(table.Entries, &TaxTableEntry{
+				book:    b,
+				Account: &Account{},
+				DbTaxTableEntry: DbTaxTableEntry{
+					Id:          int64(n + 1),
+					TaxTable:    table.Guid,
+					AmountNum:   amount.Num().Int64(),
+					AmountDenom: amount.Denom().Int64(),
+					Type:        int(tax.tp),
+				},
+			})
+		}
+		b.taxTablesByGuid[table.Guid] = table
+	}
+
+	price := testRat(t, tc.price)
+	if tc.bill {
+		e.Bill = sql.NullString{i.Guid, true}
+		e.BPriceNum = sql.NullInt64{price.Num().Int64(), true}
+		e.BPriceDenom = sql.NullInt64{price.Denom().Int64(), true}
+		e.SetBillTaxTable(table, tc.included)
+	} else {
+		e.Invoice = sql.NullString{i.Guid, true}
+		e.IPriceNum = sql.NullInt64{price.Num().Int64(), true}
+		e.IPriceDenom = sql.NullInt64{price.Denom().Int64(), true}
+		e.SetInvoiceTaxTable(table, tc.included)
+	}
+
+	if tc.discount != "" {
+		e.SetInvoiceDiscount(testRat(t, tc.discount), tc.discType, tc.discHow)
+	}
+
+	return e
+}
+
+func TestEntryComputeValues(t *testing.T) {
+	for _, tc := range entryTestCases {
+		t.Run(tc.name, func(t *testing.T) {
+			e := newTestEntry(t, tc)
+
+			net, taxes, discount, err := e.computeValues()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if want := testRat(t, tc.net); net.Cmp(want) != 0 {
+				t.Errorf("net = %s, want %s", net.FloatString(3), want.FloatString(3))
+			}
+			if want := testRat(t, tc.wantDiscount); discount.Cmp(want) != 0 {
+				t.Errorf("discount = %s, want %s", discount.FloatString(3), want.FloatString(3))
+			}
+			if len(taxes) != len(tc.tax) {
+				t.Fatalf("got %d taxes, want %d", len(taxes), len(tc.tax))
+			}
+			for n, tax := range taxes {
+				if want := testRat(t, tc.tax[n]); tax.Amount.Cmp(want) != 0 {
+					t.Errorf("tax %d = %s, want %s", n, tax.Amount.FloatString(3), want.FloatString(3))
+				}
+			}
+		})
+	}
+}
+
+func TestEntryGetSubtotal(t *testing.T) {
+	for _, tc := range entryTestCases {
+		t.Run(tc.name, func(t *testing.T) {
+			e := newTestEntry(t, tc)
+
+			net, tax, gross, err := e.GetSubtotal()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			wantTax := new(big.Rat)
+			for _, s := range tc.tax {
+				wantTax.Add(wantTax, testRat(t, s))
+			}
+			wantNet := testRat(t, tc.net)
+			wantGross := new(big.Rat).Add(wantNet, wantTax)
+
+			if net.Cmp(wantNet) != 0 || tax.Cmp(wantTax) != 0 || gross.Cmp(wantGross) != 0 {
+				t.Errorf("subtotal = %s + %s = %s, want %s + %s = %s",
+					net.FloatString(3), tax.FloatString(3), gross.FloatString(3),
+					wantNet.FloatString(3), wantTax.FloatString(3), wantGross.FloatString(3))
+			}
+		})
+	}
+}
+
+// The gross value of an entry with the price including taxes is the sum of the
+// rounded net value and taxes, not the price
+func TestEntryTaxIncludedRoundingDifference(t *testing.T) {
+	e := newTestEntry(t, entryTestCase{
+		quantity: "1", price: "10.00",
+		taxes:    []testTax{{"19", AmountTypePercent}, {"7", AmountTypePercent}},
+		included: true,
+	})
+
+	gross, err := e.GetGross()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := testRat(t, "10.01"); gross.Cmp(want) != 0 {
+		t.Errorf("gross = %s, want %s", gross.FloatString(2), want.FloatString(2))
+	}
+}
+
+func TestEntryUnsupportedDiscount(t *testing.T) {
+	e := newTestEntry(t, entryTestCase{quantity: "1", price: "100", discount: "10"})
+
+	e.IDiscType = sql.NullString{"BOGUS", true}
+	if _, _, _, err := e.computeValues(); err == nil {
+		t.Error("discount type BOGUS accepted")
+	}
+
+	e.IDiscType = sql.NullString{AmountTypePercent.String(), true}
+	e.IDiscHow = sql.NullString{"BOGUS", true}
+	if _, _, _, err := e.computeValues(); err == nil {
+		t.Error("discount application BOGUS accepted")
+	}
+}
+
+// The post transaction of each document in testdata/entries.sql holds the net
+// values per income or expense account, the taxes per tax account and the
+// gross value on the post account, which have to match the entries' values
+func TestEntryValuesOfPostedDocuments(t *testing.T) {
+	book := openTestBook(t, "entries.sql")
+
+	add := func(values map[string]*big.Rat, acc string, value *big.Rat) {
+		if values[acc] == nil {
+			values[acc] = new(big.Rat)
+		}
+		values[acc].Add(values[acc], value)
+	}
+
+	for _, i := range book.GetInvoices() {
+		i := i
+		t.Run(i.Id, func(t *testing.T) {
+			txn := i.GetPostTxn()
+			if txn == nil {
+				t.Fatal("document is not posted")
+			}
+
+			// Invoices credit income and tax accounts, bills debit them
+			sign := big.NewRat(-1, 1)
+			if i.GetOwnerType() == OwnerTypeVendor {
+				sign = big.NewRat(1, 1)
+			}
+
+			want := make(map[string]*big.Rat)
+			for _, s := range txn.Splits {
+				value := big.NewRat(s.ValueNum, s.ValueDenom)
+				add(want, s.AccountGuid, value.Mul(value, sign))
+			}
+
+			got := make(map[string]*big.Rat)
+			for _, e := range i.Entries {
+				acc := e.IAcct.String
+				if e.Bill.Valid {
+					acc = e.BAcct.String
+				}
+
+				net, taxes, err := e.GetValues()
+				if err != nil {
+					t.Fatal(err)
+				}
+				add(got, acc, net)
+				gross := new(big.Rat).Set(net)
+				for _, tax := range taxes {
+					add(got, tax.Account.Guid, tax.Amount)
+					gross.Add(gross, tax.Amount)
+				}
+				add(got, i.PostAcc.String, gross.Neg(gross))
+			}
+
+			for acc, value := range want {
+				name := book.GetAccountByGUID(acc).Name.String
+				if got[acc] == nil {
+					t.Errorf("account %s: got nothing, posted %s", name, value.FloatString(3))
+				} else if got[acc].Cmp(value) != 0 {
+					t.Errorf("account %s: got %s, posted %s", name, got[acc].FloatString(3), value.FloatString(3))
+				}
+			}
+			for acc, value := range got {
+				if want[acc] == nil {
+					t.Errorf("account %s: got %s, nothing posted", book.GetAccountByGUID(acc).Name.String,
+						value.FloatString(3))
+				}
+			}
+		})
+	}
+}
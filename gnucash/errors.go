@@ -0,0 +1,57 @@
+package gnucash
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// Kinds of errors returned by this package. Errors are wrapped with context,
+// use errors.Is to check for a kind, e.g. errors.Is(err, ErrNotFound).
+var (
+	// An object (account, invoice, slot, ...) doesn't exist
+	ErrNotFound = errors.New("not found")
+	// A change would violate a constraint of the book (e.g. a duplicate name)
+	ErrConstraint = errors.New("constraint violation")
+	// A GnuCash feature is used that this package doesn't implement
+	ErrUnsupported = errors.New("unsupported")
+	// The book contains inconsistent data (e.g. dangling references)
+	ErrCorruptBook = errors.New("corrupt book")
+	// The book is locked by GnuCash or another process, see Book.Lock
+	ErrLocked = errors.New("locked")
+)
+
+// Error of a database operation. Violated SQLite constraints match
+// ErrConstraint.
+type DBError struct {
+	Op  string
+	Err error
+}
+
+func (e *DBError) Error() string {
+	return e.Op + ": " + e.Err.Error()
+}
+
+func (e *DBError) Unwrap() error {
+	return e.Err
+}
+
+func (e *DBError) Is(target error) bool {
+	var se sqlite3.Error
+	return target == ErrConstraint && errors.As(e.Err, &se) && se.Code == sqlite3.ErrConstraint
+}
+
+// Wraps err of a database operation (nil if err is nil)
+func dbError(err error, format string, a ...interface{}) error {
+	if err == nil {
+		return nil
+	}
+
+	return &DBError{Op: fmt.Sprintf(format, a...), Err: err}
+}
+
+// Returns an error of the given kind with context
+func newError(kind error, format string, a ...interface{}) error {
+	return fmt.Errorf("%s: %w", fmt.Sprintf(format, a...), kind)
+}
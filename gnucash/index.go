@@ -0,0 +1,55 @@
+package gnucash
+
+// Index of object GUIDs by a key several objects may share (e.g. a name or an
+// ID), lookups return the object indexed first
+type keyIndex map[string][]string
+
+func (x keyIndex) add(key string, guid string) {
+	x[key] = append(x[key], guid)
+}
+
+func (x keyIndex) remove(key string, guid string) {
+	guids := x[key]
+	for n, g := range guids {
+		if g == guid {
+			guids = append(guids[:n:n], guids[n+1:]...)
+			break
+		}
+	}
+
+	if len(guids) == 0 {
+		delete(x, key)
+	} else {
+		x[key] = guids
+	}
+}
+
+// Indexes guid under key, moving it from the key it was indexed under before
+// (old, if indexed) if that changed
+func (x keyIndex) update(guid string, indexed bool, old string, key string) {
+	if indexed {
+		if old == key {
+			return
+		}
+		x.remove(old, guid)
+	}
+
+	x.add(key, guid)
+}
+
+// Returns the GUID indexed first under key, "" if there is none
+func (x keyIndex) first(key string) string {
+	if guids := x[key]; len(guids) > 0 {
+		return guids[0]
+	}
+
+	return ""
+}
+
+// Keys an object is currently indexed under, to move it in the indexes when
+// it is renamed
+type indexedKeys struct {
+	ok   bool
+	name string
+	id   string
+}
@@ -2,16 +2,20 @@ package gnucash
 
 import (
 	"database/sql"
-	"log"
+	"fmt"
 	"math/big"
+	"time"
 )
 
 // Represents an invoice, bill, expense voucher or credit note.
 type Invoice struct {
 	book         *Book
 	IsCreditNote bool
-	Entries      []*Entry
+	Entries      []*Entry // all entries unless the book is loaded lazily, see GetEntries
 	DbInvoice
+
+	entriesLoaded  bool
+	indexedPostTxn string // post transaction GUID the invoice is indexed under
 }
 
 type DbInvoice struct {
@@ -43,7 +47,36 @@ func (i *Invoice) GetOwnerType() OwnerType {
 	return OwnerType(i.OwnerType.Int32)
 }
 
+// Returns the job owning the invoice, or nil if it isn't owned by a job
+func (i *Invoice) GetJob() *Job {
+	if i.GetOwnerType() != OwnerTypeJob || !i.OwnerGuid.Valid {
+		return nil
+	}
+
+	return i.book.GetJobByGUID(i.OwnerGuid.String)
+}
+
+// Returns the type of the customer, vendor or employee the invoice belongs to,
+// resolving jobs to their owners
+func (i *Invoice) GetEndOwnerType() OwnerType {
+	if i.GetOwnerType() != OwnerTypeJob {
+		return i.GetOwnerType()
+	}
+
+	j := i.GetJob()
+	if j == nil {
+		return OwnerTypeUndefined
+	}
+
+	return j.GetOwnerType()
+}
+
+// Returns the vendor of the invoice (directly or via its job)
 func (i *Invoice) GetVendor() *Vendor {
+	if j := i.GetJob(); j != nil {
+		return j.GetVendor()
+	}
+
 	if i.GetOwnerType() != OwnerTypeVendor || !i.OwnerGuid.Valid {
 		return nil
 	}
@@ -51,6 +84,40 @@ func (i *Invoice) GetVendor() *Vendor {
 	return i.book.GetVendorByGUID(i.OwnerGuid.String)
 }
 
+// Returns the customer of the invoice (directly or via its job)
+func (i *Invoice) GetCustomer() *Customer {
+	if j := i.GetJob(); j != nil {
+		return j.GetCustomer()
+	}
+
+	if i.GetOwnerType() != OwnerTypeCustomer || !i.OwnerGuid.Valid {
+		return nil
+	}
+
+	return i.book.GetCustomerByGUID(i.OwnerGuid.String)
+}
+
+// Returns the name of the owner (customer, vendor or employee) of the invoice,
+// or "" if the owner can't be resolved
+func (i *Invoice) GetOwnerName() string {
+	switch i.GetEndOwnerType() {
+	case OwnerTypeCustomer:
+		if c := i.GetCustomer(); c != nil {
+			return c.Name
+		}
+	case OwnerTypeVendor:
+		if v := i.GetVendor(); v != nil {
+			return v.Name
+		}
+	case OwnerTypeEmployee:
+		if e := i.GetEmployee(); e != nil {
+			return e.AddrName.String
+		}
+	}
+
+	return ""
+}
+
 func (i *Invoice) GetEmployee() *Employee {
 	if i.GetOwnerType() != OwnerTypeEmployee || !i.OwnerGuid.Valid {
 		return nil
@@ -59,7 +126,7 @@ func (i *Invoice) GetEmployee() *Employee {
 	return i.book.GetEmployeeByGUID(i.OwnerGuid.String)
 }
 
-func (i *Invoice) Write() {
+func (i *Invoice) Write() error {
 	query := `INSERT OR REPLACE INTO invoices ("guid", "id", "date_opened", "date_posted",
 			"notes", "active", "currency", "owner_type", "owner_guid", "terms",
 			"billing_id", "post_txn", "post_lot", "post_acc", "billto_type",
@@ -70,18 +137,44 @@ func (i *Invoice) Write() {
 			:charge_amt_denom)`
 	_, err := i.book.DB.NamedExec(query, i.DbInvoice)
 	if err != nil {
-		log.Fatal(err)
+		return dbError(err, "writing invoice %s", i.Id)
 	}
+
+	i.indexPostTxn()
+	return nil
 }
 
-func (i *Invoice) AddEntry(e *Entry) {
+// Updates the book's index of invoices by post transaction
+func (i *Invoice) indexPostTxn() {
+	txn := ""
+	if i.PostTxn.Valid {
+		txn = i.PostTxn.String
+	}
+
+	if txn == i.indexedPostTxn {
+		return
+	}
+	if i.indexedPostTxn != "" && i.book.invoicesByPostTxn[i.indexedPostTxn] == i {
+		delete(i.book.invoicesByPostTxn, i.indexedPostTxn)
+	}
+	if txn != "" {
+		i.book.invoicesByPostTxn[txn] = i
+	}
+	i.indexedPostTxn = txn
+}
+
+func (i *Invoice) AddEntry(e *Entry) error {
+	if err := i.book.checkComplete("adding an entry to invoice " + i.Id); err != nil {
+		return err
+	}
+
 	if e.Guid == "" {
 		e.Guid = NewGuid()
 	}
 
 	e.book = i.book
 
-	switch i.GetOwnerType() {
+	switch i.GetEndOwnerType() {
 	case OwnerTypeCustomer:
 		e.Invoice.Valid = true
 		e.Invoice.String = i.Guid
@@ -91,31 +184,136 @@ func (i *Invoice) AddEntry(e *Entry) {
 		e.Bill.Valid = true
 		e.Bill.String = i.Guid
 	default:
-		log.Fatalf("Unimplemented invoice type %d in AddEntry()\n",
-			i.GetOwnerType())
+		return newError(ErrUnsupported, "adding entries to invoice %s of owner type %d",
+			i.Id, i.GetEndOwnerType())
 	}
 
-	e.Write()
+	if err := e.Write(); err != nil {
+		return err
+	}
 
 	i.book.entries = append(i.book.entries, e)
 	i.Entries = append(i.Entries, e)
+
+	return nil
+}
+
+// Returns the entries of the invoice, loading them first if the book is loaded
+// lazily
+func (i *Invoice) GetEntries() ([]*Entry, error) {
+	if i.entriesLoaded {
+		return i.Entries, nil
+	}
+
+	if err := i.book.loadEntries("invoice = ? OR bill = ?", i.Guid, i.Guid); err != nil {
+		return nil, err
+	}
+	i.entriesLoaded = true
+
+	return i.Entries, nil
 }
 
-func (i *Invoice) GetTotal() *big.Rat {
+// Returns the total of the invoice including taxes
+func (i *Invoice) GetTotal() (*big.Rat, error) {
 	tot := big.NewRat(0, 1)
 
-	for _, e := range i.Entries {
-		tot.Add(tot, e.GetSubtotal())
+	entries, err := i.GetEntries()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, e := range entries {
+		gross, err := e.GetGross()
+		if err != nil {
+			return nil, err
+		}
+		tot.Add(tot, gross)
+	}
+
+	return tot, nil
+}
+
+// Returns the billing terms of the invoice, nil if it has none
+func (i *Invoice) GetTerms() *BillTerm {
+	if !i.Terms.Valid {
+		return nil
+	}
+
+	return i.book.GetBillTermByGUID(i.Terms.String)
+}
+
+// Sets the billing terms of the invoice, nil for none (call Write to save them)
+func (i *Invoice) SetTerms(t *BillTerm) {
+	i.Terms = sql.NullString{}
+	if t != nil {
+		i.Terms = sql.NullString{t.Guid, true}
+	}
+}
+
+// Returns the due date of the invoice if it is posted on postDate (format
+// "YYYY-MM-DD hh:mm:ss"), computed from its billing terms. Invoices without
+// terms are due on the post date.
+func (i *Invoice) ComputeDueDate(postDate string) (string, error) {
+	t := i.GetTerms()
+	if t == nil {
+		return postDate, nil
+	}
+
+	d, err := time.Parse("2006-01-02 15:04:05", postDate)
+	if err != nil {
+		return "", fmt.Errorf("post date of invoice %s: %w", i.Id, err)
+	}
+
+	due, err := t.ComputeDueDate(d)
+	if err != nil {
+		return "", err
+	}
+
+	return due.Format("2006-01-02 15:04:05"), nil
+}
+
+// Returns the due date of a posted invoice ("" if it is not posted)
+func (i *Invoice) GetDateDue() string {
+	txn := i.GetPostTxn()
+	if txn == nil {
+		return ""
+	}
+
+	if s := i.book.GetSlot(txn.Guid, "trans-date-due"); s != nil {
+		return s.TimespecVal.String
 	}
 
-	return tot
+	return ""
 }
 
-// Post invoice to account a
-func (i *Invoice) Post(a *Account) {
+// Post invoice to account a with the given post and due dates (format
+// "YYYY-MM-DD hh:mm:ss"). If dueDate is empty, it is computed from the billing
+// terms of the invoice. If the invoice has no open date, it is set to the post
+// date.
+func (i *Invoice) Post(a *Account, postDate string, dueDate string) error {
+	if err := i.book.checkComplete("posting invoice " + i.Id); err != nil {
+		return err
+	}
+
 	if i.DatePosted.Valid {
-		log.Fatalf("Could not post invoice %s because it is already posted\n",
-			i.Guid)
+		return newError(ErrConstraint, "invoice %s is already posted", i.Id)
+	}
+
+	action := i.GetTypeString()
+	if action == "" {
+		return newError(ErrUnsupported, "posting invoice %s of owner type %d",
+			i.Id, i.GetEndOwnerType())
+	}
+
+	if dueDate == "" {
+		var err error
+		if dueDate, err = i.ComputeDueDate(postDate); err != nil {
+			return err
+		}
+	}
+
+	if !i.DateOpened.Valid {
+		i.DateOpened = sql.NullString{postDate, true}
 	}
 
 	// Create lot for txns
@@ -125,16 +323,17 @@ func (i *Invoice) Post(a *Account) {
 			IsClosed:    -1, // this is a cache value, -1 means cache invalid
 		},
 	}
-	a.AddLot(lot)
+	if err := a.AddLot(lot); err != nil {
+		return err
+	}
 
-	lot.SetInvoice(i)
+	if err := lot.SetInvoice(i); err != nil {
+		return err
+	}
 
-	desc := "ERROR"
-	switch i.GetOwnerType() {
-	case OwnerTypeVendor:
-		desc = i.GetVendor().Name
-	case OwnerTypeEmployee:
-		desc = i.GetEmployee().AddrName.String
+	desc := i.GetOwnerName()
+	if desc == "" {
+		desc = "ERROR"
 	}
 
 	// Create txn
@@ -143,91 +342,133 @@ func (i *Invoice) Post(a *Account) {
 			CurrencyGuid: i.Currency,
 			Num:          i.Id,
 			EnterDate:    sql.NullString{GetCurrentTimeString(), true},
-			PostDate:     sql.NullString{GetCurrentTimeString(), true},
+			PostDate:     sql.NullString{postDate, true},
 			Description:  sql.NullString{desc, true},
 		},
 	}
-	i.book.AddTransaction(txn)
+	if err := i.book.AddTransaction(txn); err != nil {
+		return err
+	}
+
+	if err := txn.SetType(TransactionTypeInvoice); err != nil {
+		return err
+	}
+	if err := txn.SetReadOnly(true); err != nil {
+		return err
+	}
+	if err := txn.SetDateDue(dueDate); err != nil {
+		return err
+	}
 
-	txn.SetType(TransactionTypeInvoice)
-	txn.SetReadOnly(true)
+	// Entries of expense vouchers paid by card are booked against the credit
+	// card account of the employee instead of the post account
+	var ccard *Account
+	if i.GetEndOwnerType() == OwnerTypeEmployee {
+		if e := i.GetEmployee(); e != nil && e.CCardGuid.Valid {
+			ccard = i.book.GetAccountByGUID(e.CCardGuid.String)
+		}
+	}
 
-	// Create splits (accumulate per dest. account)
+	// Create splits (accumulate per dest. account and tax account). Entry
+	// quantities of credit notes are stored negated (as GnuCash does), which
+	// reverses their splits.
 	splits := []*Split{}
-	for _, e := range i.Entries {
-		// Attempt to find existing split for dest. account
-		found := false
+	ccardSplits := []*Split{}
+	total := big.NewRat(0, 1)
+	addValue := func(acc *Account, val *big.Rat) {
+		// Attempt to find existing split for the account
 		for _, s := range splits {
-			var destAcc string
-			if e.BAcct.Valid {
-				destAcc = e.BAcct.String
-			} else if e.IAcct.Valid {
-				destAcc = e.IAcct.String
-			} else {
-				log.Fatalf("Entry %s has neither b_acct nor i_acct set", e.Guid)
-			}
-
-			if s.AccountGuid == destAcc {
-				val := big.NewRat(s.ValueNum, s.ValueDenom)
-				val.Add(val, e.GetSubtotal())
-				s.ValueNum = val.Num().Int64()
-				s.ValueDenom = val.Denom().Int64()
+			if s.AccountGuid == acc.Guid {
+				sval := big.NewRat(s.ValueNum, s.ValueDenom)
+				sval.Add(sval, val)
+				s.ValueNum = sval.Num().Int64()
+				s.ValueDenom = sval.Denom().Int64()
 				s.QuantityNum = s.ValueNum
 				s.QuantityDenom = s.ValueDenom
-				found = true
-				break
+				return
 			}
 		}
 
 		// If no existing split has been found, create a new one
-		if !found {
-			var acc *Account
-			var action string
-			if e.BAcct.Valid {
-				action = "Bill"
-				acc = i.book.GetAccountByGUID(e.BAcct.String)
-			} else if e.IAcct.Valid {
-				action = "Invoice"
-				acc = i.book.GetAccountByGUID(e.IAcct.String)
-			}
+		splits = append(splits, &Split{
+			Account: acc,
+			DbSplit: DbSplit{
+				AccountGuid:    acc.Guid,
+				Action:         action,
+				ReconcileState: "n",
+				ValueNum:       val.Num().Int64(),
+				ValueDenom:     val.Denom().Int64(),
+				QuantityNum:    val.Num().Int64(),
+				QuantityDenom:  val.Denom().Int64(),
+			},
+		})
+	}
+
+	for _, e := range i.Entries {
+		var destAcc string
+		if e.BAcct.Valid {
+			destAcc = e.BAcct.String
+		} else if e.IAcct.Valid {
+			destAcc = e.IAcct.String
+		} else {
+			return newError(ErrCorruptBook, "entry %s has neither b_acct nor i_acct set", e.Guid)
+		}
 
-			if acc == nil {
-				log.Fatalf("Entry %s has no destination account (or dest. account GUID is invalid)")
+		acc := i.book.GetAccountByGUID(destAcc)
+		if acc == nil {
+			return newError(ErrCorruptBook, "destination account %s of entry %s not found",
+				destAcc, e.Guid)
+		}
+
+		// Income (and taxes) of customer invoices are booked as credit
+		net, taxes, err := e.GetValues()
+		if err != nil {
+			return err
+		}
+		gross := new(big.Rat).Set(net)
+		for _, t := range taxes {
+			gross.Add(gross, t.Amount)
+		}
+		if i.GetEndOwnerType() == OwnerTypeCustomer {
+			net.Neg(net)
+			gross.Neg(gross)
+			for _, t := range taxes {
+				t.Amount.Neg(t.Amount)
 			}
+		}
 
-			val := e.GetSubtotal()
+		if e.GetPaymentType() == EntryPaymentTypeCard && i.GetOwnerType() == OwnerTypeEmployee {
+			if ccard == nil {
+				return newError(ErrConstraint, "entry %s is paid by card, but the employee of"+
+					" invoice %s has no (valid) credit card account", e.Guid, i.Id)
+			}
 
-			s := &Split{
-				Account: acc,
+			cval := new(big.Rat).Neg(gross)
+			ccardSplits = append(ccardSplits, &Split{
+				Account: ccard,
 				DbSplit: DbSplit{
+					Memo:           e.Description.String,
 					Action:         action,
 					ReconcileState: "n",
-					ValueNum:       val.Num().Int64(),
-					ValueDenom:     val.Denom().Int64(),
-					QuantityNum:    val.Num().Int64(),
-					QuantityDenom:  val.Denom().Int64(),
-					LotGuid:        sql.NullString{lot.Guid, true},
+					ValueNum:       cval.Num().Int64(),
+					ValueDenom:     cval.Denom().Int64(),
+					QuantityNum:    cval.Num().Int64(),
+					QuantityDenom:  cval.Denom().Int64(),
 				},
-			}
-			splits = append(splits, s)
+			})
+		} else {
+			total.Add(total, gross)
 		}
-	}
 
-	// Create A/P split with total amount
-	var action string
-	switch i.GetOwnerType() {
-	case OwnerTypeVendor:
-		action = "Bill"
-	case OwnerTypeCustomer:
-		action = "Invoice"
-	case OwnerTypeEmployee:
-		action = "Credit Note"
-	default:
-		log.Fatalf("Invoice posting for owner type %d not implemented\n", i.GetOwnerType())
+		addValue(acc, net)
+		for _, t := range taxes {
+			addValue(t.Account, t.Amount)
+		}
 	}
 
-	val := i.GetTotal()
-	val.Neg(val)
+	// Create split for the post account with the total amount (except entries
+	// paid by card)
+	val := total.Neg(total)
 
 	s := &Split{
 		Account: a,
@@ -241,19 +482,45 @@ func (i *Invoice) Post(a *Account) {
 			LotGuid:        sql.NullString{lot.Guid, true},
 		},
 	}
-	txn.AddSplit(s)
+	if err := txn.AddSplit(s); err != nil {
+		return err
+	}
 
 	// Add generated (and accumulated) splits to txn
-	for _, s := range splits {
-		txn.AddSplit(s)
+	for _, s := range append(splits, ccardSplits...) {
+		if err := txn.AddSplit(s); err != nil {
+			return err
+		}
+	}
+	if err := lot.updateClosed(); err != nil {
+		return err
 	}
 
 	// Set post date, acc, txn and lot
-	i.DatePosted = sql.NullString{GetCurrentTimeString(), true}
+	i.DatePosted = sql.NullString{postDate, true}
 	i.PostAcc = sql.NullString{a.Guid, true}
 	i.PostTxn = sql.NullString{txn.Guid, true}
 	i.PostLot = sql.NullString{lot.Guid, true}
-	i.Write()
+	return i.Write()
+}
+
+// Returns the type of the invoice as GnuCash uses it for the actions of the
+// splits of posting txns, "" if the owner type has none
+func (i *Invoice) GetTypeString() string {
+	if i.IsCreditNote {
+		return "Credit Note"
+	}
+
+	switch i.GetEndOwnerType() {
+	case OwnerTypeCustomer:
+		return "Invoice"
+	case OwnerTypeVendor:
+		return "Bill"
+	case OwnerTypeEmployee:
+		return "Expense"
+	}
+
+	return ""
 }
 
 func (i *Invoice) GetPostLot() *Lot {
@@ -264,22 +531,185 @@ func (i *Invoice) GetPostLot() *Lot {
 	return i.book.GetLotByGUID(i.PostLot.String)
 }
 
-func (i *Invoice) AssignPayment(s *Split) {
+// Assigns split s (of a payment txn) to the invoice. Several (partial) payments
+// may be assigned to the same invoice, its post lot is closed once they cover
+// the invoice total. Payments exceeding the amount due are handled as GnuCash
+// does, leaving a credit balance with the invoice owner: the excess is moved to
+// a new split in a pre-payment lot of the owner, or all of s if nothing is due
+// (e.g. the invoice is paid already). Returns the split holding the credit (s
+// itself or the new split), nil if the invoice took all of s.
+func (i *Invoice) AssignPayment(s *Split) (*Split, error) {
+	if err := i.book.checkComplete("assigning a payment to invoice " + i.Id); err != nil {
+		return nil, err
+	}
+
 	// Assign invoice post lot to split
 	lot := i.GetPostLot()
 	if lot == nil {
-		log.Fatalf("could not get post lot for invoice %s\n", i.Guid)
+		return nil, newError(ErrConstraint, "invoice %s has no post lot", i.Id)
 	}
 
-	s.LotGuid = sql.NullString{lot.Guid, true}
-	s.Write()
+	var credit *Split
+	bal := lot.GetBalance()
+	amount := big.NewRat(s.QuantityNum, s.QuantityDenom)
+	if bal.Sign() == 0 || amount.Sign() == bal.Sign() {
+		// Nothing due that s could pay
+		credit = s
+	} else if rest := new(big.Rat).Add(bal, amount); rest.Sign() == amount.Sign() {
+		// Split off the excess if the payment turns the lot balance around
+		var err error
+		if credit, err = s.splitOff(rest); err != nil {
+			return nil, err
+		}
+	}
+
+	if credit != s {
+		if err := s.SetLot(lot); err != nil {
+			return nil, err
+		}
+	}
+
+	if credit != nil {
+		pLot := &Lot{
+			DbLot: DbLot{
+				AccountGuid: sql.NullString{credit.AccountGuid, true},
+			},
+		}
+		if err := credit.Account.AddLot(pLot); err != nil {
+			return nil, err
+		}
+		if err := pLot.SetOwner(i.GetOwnerType(), i.OwnerGuid.String); err != nil {
+			return nil, err
+		}
+		if err := credit.SetLot(pLot); err != nil {
+			return nil, err
+		}
+	}
 
 	// Set txn type to payment
-	s.Transaction.SetType(TransactionTypePayment)
+	if err := s.Transaction.SetType(TransactionTypePayment); err != nil {
+		return nil, err
+	}
 
 	// Set action for all splits in txn to payment
 	for _, ts := range s.Transaction.Splits {
 		ts.Action = "Payment"
-		ts.Write()
+		if err := ts.Write(); err != nil {
+			return nil, err
+		}
 	}
+
+	return credit, nil
+}
+
+// Returns true if the invoice is posted and its post lot is closed, i.e. the
+// assigned payments cover the invoice total
+func (i *Invoice) IsPaid() bool {
+	lot := i.GetPostLot()
+	return lot != nil && lot.IsClosed == 1
+}
+
+// Returns the amount still due on a posted invoice, i.e. its total minus the
+// payments assigned to it
+func (i *Invoice) GetAmountDue() (*big.Rat, error) {
+	lot := i.GetPostLot()
+	if lot == nil {
+		return nil, newError(ErrConstraint, "invoice %s has no post lot", i.Id)
+	}
+
+	// Bills and vouchers are posted as credit, invoices as debit
+	due := lot.GetBalance()
+	if i.GetEndOwnerType() != OwnerTypeCustomer {
+		due.Neg(due)
+	}
+
+	return due, nil
+}
+
+func (i *Invoice) GetPostTxn() *Transaction {
+	if !i.PostTxn.Valid {
+		return nil
+	}
+
+	return i.book.GetTransactionByGUID(i.PostTxn.String)
+}
+
+// Unpost invoice by removing its posting transaction (including splits). As in
+// GnuCash, the post lot is removed as well unless payments are still assigned
+// to it, in which case it is kept as a pre-payment lot of the invoice owner.
+func (i *Invoice) Unpost() error {
+	if err := i.book.checkComplete("unposting invoice " + i.Id); err != nil {
+		return err
+	}
+
+	if !i.DatePosted.Valid {
+		return newError(ErrConstraint, "invoice %s is not posted", i.Id)
+	}
+
+	txn := i.GetPostTxn()
+	if txn != nil {
+		if err := txn.remove(); err != nil {
+			return err
+		}
+	}
+
+	lot := i.GetPostLot()
+	if lot != nil {
+		if err := lot.removeInvoice(); err != nil {
+			return err
+		}
+
+		if len(lot.GetSplits()) > 0 {
+			if err := lot.SetOwner(i.GetOwnerType(), i.OwnerGuid.String); err != nil {
+				return err
+			}
+			if err := lot.updateClosed(); err != nil {
+				return err
+			}
+		} else if err := lot.remove(); err != nil {
+			return err
+		}
+	}
+
+	i.DatePosted = sql.NullString{}
+	i.PostAcc = sql.NullString{}
+	i.PostTxn = sql.NullString{}
+	i.PostLot = sql.NullString{}
+	return i.Write()
+}
+
+// Deletes an unposted invoice including its entries and slots
+func (i *Invoice) Delete() error {
+	if err := i.book.checkComplete("deleting invoice " + i.Id); err != nil {
+		return err
+	}
+
+	if i.DatePosted.Valid {
+		return newError(ErrConstraint, "invoice %s is posted, unpost it first", i.Id)
+	}
+
+	for len(i.Entries) > 0 {
+		if err := i.Entries[0].Delete(); err != nil {
+			return err
+		}
+	}
+
+	_, err := i.book.DB.Exec(`DELETE FROM "invoices" WHERE "guid"=?`, i.Guid)
+	if err != nil {
+		return dbError(err, "deleting invoice %s", i.Id)
+	}
+
+	if err := i.book.removeSlotsForObj(i.Guid); err != nil {
+		return err
+	}
+
+	delete(i.book.invoicesByGuid, i.Guid)
+	for n, x := range i.book.invoices {
+		if x == i {
+			i.book.invoices = append(i.book.invoices[:n], i.book.invoices[n+1:]...)
+			break
+		}
+	}
+
+	return nil
 }
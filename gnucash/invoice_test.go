@@ -0,0 +1,216 @@
+package gnucash
+
+import (
+	"database/sql"
+	"errors"
+	"math/big"
+	"testing"
+)
+
+// Adds a vendor bill of 100.00 EUR posted to Accounts Payable
+func addTestBill(t *testing.T, book *Book) *Invoice {
+	v := &Vendor{DbVendor: DbVendor{Name: "Vendor", Active: 1}}
+	if err := book.AddVendor(v); err != nil {
+		t.Fatal(err)
+	}
+
+	i := &Invoice{
+		DbInvoice: DbInvoice{
+			DateOpened:     sql.NullString{"2021-03-01 10:59:00", true},
+			Active:         1,
+			Currency:       "eur00000000000000000000000000001",
+			OwnerType:      sql.NullInt32{int32(OwnerTypeVendor), true},
+			OwnerGuid:      sql.NullString{v.Guid, true},
+			ChargeAmtNum:   sql.NullInt64{0, true},
+			ChargeAmtDenom: sql.NullInt64{1, true},
+		},
+	}
+	if err := book.AddInvoice(i); err != nil {
+		t.Fatal(err)
+	}
+
+	e := &Entry{
+		DbEntry: DbEntry{
+			Date:          "2021-03-01 10:59:00",
+			QuantityNum:   sql.NullInt64{1, true},
+			QuantityDenom: sql.NullInt64{1, true},
+			BAcct:         sql.NullString{"expn0000000000000000000000000001", true},
+			BPriceNum:     sql.NullInt64{10000, true},
+			BPriceDenom:   sql.NullInt64{100, true},
+		},
+	}
+	if err := i.AddEntry(e); err != nil {
+		t.Fatal(err)
+	}
+
+	ap := book.GetAccountByGUID("ap000000000000000000000000000001")
+	if err := i.Post(ap, "2021-03-01 10:59:00", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	return i
+}
+
+// Adds a payment of amount (in cents) from the bank account, returns its split
+// in Accounts Payable
+func addTestPayment(t *testing.T, book *Book, amount int64) *Split {
+	txn := &Transaction{
+		DbTransaction: DbTransaction{
+			CurrencyGuid: "eur00000000000000000000000000001",
+			PostDate:     sql.NullString{"2021-03-15 10:59:00", true},
+			EnterDate:    sql.NullString{"2021-03-15 10:59:00", true},
+		},
+	}
+	if err := book.AddTransaction(txn); err != nil {
+		t.Fatal(err)
+	}
+
+	split := func(acc string, amount int64) *Split {
+		s := &Split{
+			DbSplit: DbSplit{
+				ReconcileState: "n",
+				ValueNum:       amount,
+				ValueDenom:     100,
+				QuantityNum:    amount,
+				QuantityDenom:  100,
+			},
+			Account: book.GetAccountByGUID(acc),
+		}
+		if err := txn.AddSplit(s); err != nil {
+			t.Fatal(err)
+		}
+		return s
+	}
+	split("bank0000000000000000000000000001", -amount)
+
+	return split("ap000000000000000000000000000001", amount)
+}
+
+func TestInvoiceAssignPayment(t *testing.T) {
+	cases := []struct {
+		name     string
+		payments []int64 // in cents, the last one is checked
+		credit   string  // "" if the bill takes all of the payment
+		paid     bool
+	}{
+		{"exact", []int64{10000}, "", true},
+		{"partial", []int64{4000}, "", false},
+		{"partial payments", []int64{4000, 6000}, "", true},
+		{"overpaid", []int64{13000}, "30", true},
+		{"overpaid by second payment", []int64{4000, 8000}, "20", true},
+		{"already paid", []int64{10000, 5000}, "50", true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			book := openTestBook(t, "book.sql")
+			bill := addTestBill(t, book)
+
+			var s, credit *Split
+			for _, amount := range c.payments {
+				s = addTestPayment(t, book, amount)
+
+				var err error
+				if credit, err = bill.AssignPayment(s); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if bill.IsPaid() != c.paid {
+				t.Errorf("paid %v, want %v", bill.IsPaid(), c.paid)
+			}
+			if got := bill.GetPostLot().IsClosed == 1; got != c.paid {
+				t.Errorf("post lot closed %v, want %v", got, c.paid)
+			}
+
+			if c.credit == "" {
+				if credit != nil {
+					t.Fatalf("credit split %s, want none", credit.Guid)
+				}
+				if s.LotGuid.String != bill.PostLot.String {
+					t.Errorf("payment in lot %s, want post lot %s", s.LotGuid.String, bill.PostLot.String)
+				}
+				return
+			}
+
+			if credit == nil {
+				t.Fatal("no credit split")
+			}
+			want, _ := new(big.Rat).SetString(c.credit)
+			if got := big.NewRat(credit.QuantityNum, credit.QuantityDenom); got.Cmp(want) != 0 {
+				t.Errorf("credit %s, want %s", got.FloatString(2), c.credit)
+			}
+			if credit != s && s.LotGuid.String != bill.PostLot.String {
+				t.Errorf("payment in lot %s, want post lot %s", s.LotGuid.String, bill.PostLot.String)
+			}
+
+			// Credit is kept in an open pre-payment lot of the vendor
+			lot := book.GetLotByGUID(credit.LotGuid.String)
+			if lot == nil || lot.Guid == bill.PostLot.String {
+				t.Fatalf("credit in lot %s, want a pre-payment lot", credit.LotGuid.String)
+			}
+			if lot.IsClosed != 0 {
+				t.Error("pre-payment lot is closed")
+			}
+			if slot := book.GetSlot(lot.Guid, "gncOwner/owner-guid"); slot == nil || slot.GuidVal.String != bill.OwnerGuid.String {
+				t.Error("pre-payment lot is not owned by the vendor")
+			}
+		})
+	}
+}
+
+func TestLotGetSplits(t *testing.T) {
+	book := openTestBook(t, "book.sql")
+	bill := addTestBill(t, book)
+	lot := bill.GetPostLot()
+
+	s := addTestPayment(t, book, 4000)
+	if _, err := bill.AssignPayment(s); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(lot.GetSplits()); n != 2 {
+		t.Errorf("%d splits in post lot, want 2", n)
+	}
+
+	if err := s.SetLot(nil); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(lot.GetSplits()); n != 1 {
+		t.Errorf("%d splits in post lot after unassigning payment, want 1", n)
+	}
+
+	if err := s.SetLot(lot); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Transaction.Delete(); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(lot.GetSplits()); n != 1 {
+		t.Errorf("%d splits in post lot after deleting payment, want 1", n)
+	}
+}
+
+func TestTransactionDeletePostTxn(t *testing.T) {
+	book := openTestBook(t, "book.sql")
+	bill := addTestBill(t, book)
+	txn := bill.GetPostTxn()
+
+	if err := txn.Delete(); !errors.Is(err, ErrConstraint) {
+		t.Errorf("deleting post transaction: error %v, want %v", err, ErrConstraint)
+	}
+
+	// Reposting moves the invoice to its new post transaction
+	if err := bill.Unpost(); err != nil {
+		t.Fatal(err)
+	}
+	if i := book.invoicesByPostTxn[txn.Guid]; i != nil {
+		t.Errorf("unposted bill still indexed under post transaction %s", txn.Guid)
+	}
+	if err := bill.Post(book.GetAccountByGUID("ap000000000000000000000000000001"),
+		"2021-03-02 10:59:00", ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := bill.GetPostTxn().Delete(); !errors.Is(err, ErrConstraint) {
+		t.Errorf("deleting new post transaction: error %v, want %v", err, ErrConstraint)
+	}
+}
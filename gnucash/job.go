@@ -0,0 +1,79 @@
+package gnucash
+
+import "database/sql"
+
+// Represents a job (project) of a customer or vendor, invoices and bills can be
+// owned by a job instead of the customer or vendor directly
+type Job struct {
+	book *Book
+	DbJob
+
+	indexed indexedKeys // keys of the book's indexes, see index
+}
+
+type DbJob struct {
+	Guid      string
+	Id        string
+	Name      string
+	Reference string
+	Active    int
+	OwnerType sql.NullInt32  `db:"owner_type"`
+	OwnerGuid sql.NullString `db:"owner_guid"`
+}
+
+func (j *Job) Write() error {
+	query := `INSERT OR REPLACE INTO "jobs" ("guid", "id", "name", "reference",
+			"active", "owner_type", "owner_guid")
+		VALUES (:guid, :id, :name, :reference, :active, :owner_type,
+			:owner_guid)`
+
+	_, err := j.book.DB.NamedExec(query, j.DbJob)
+	if err != nil {
+		return dbError(err, "writing job %s", j.Name)
+	}
+
+	j.index()
+	return nil
+}
+
+// Updates the book's indexes by name and ID
+func (j *Job) index() {
+	j.book.jobsByName.update(j.Guid, j.indexed.ok, j.indexed.name, j.Name)
+	j.book.jobsById.update(j.Guid, j.indexed.ok, j.indexed.id, j.Id)
+	j.indexed = indexedKeys{true, j.Name, j.Id}
+}
+
+func (j *Job) GetOwnerType() OwnerType {
+	if !j.OwnerType.Valid {
+		return OwnerTypeUndefined
+	}
+
+	return OwnerType(j.OwnerType.Int32)
+}
+
+func (j *Job) GetCustomer() *Customer {
+	if j.GetOwnerType() != OwnerTypeCustomer || !j.OwnerGuid.Valid {
+		return nil
+	}
+
+	return j.book.GetCustomerByGUID(j.OwnerGuid.String)
+}
+
+func (j *Job) GetVendor() *Vendor {
+	if j.GetOwnerType() != OwnerTypeVendor || !j.OwnerGuid.Valid {
+		return nil
+	}
+
+	return j.book.GetVendorByGUID(j.OwnerGuid.String)
+}
+
+// Sets the customer or vendor the job belongs to (call Write to save it)
+func (j *Job) SetOwner(ownerType OwnerType, ownerGuid string) error {
+	if ownerType != OwnerTypeCustomer && ownerType != OwnerTypeVendor {
+		return newError(ErrConstraint, "job %s can't be owned by owner type %d", j.Name, ownerType)
+	}
+
+	j.OwnerType = sql.NullInt32{int32(ownerType), true}
+	j.OwnerGuid = sql.NullString{ownerGuid, true}
+	return nil
+}
@@ -0,0 +1,115 @@
+package gnucash
+
+import (
+	"fmt"
+	"os"
+)
+
+// Lock of a book as taken by GnuCash (or Book.Lock) while the book is open
+type Lock struct {
+	Hostname string
+	PID      int
+}
+
+func (l *Lock) String() string {
+	return fmt.Sprintf("process %d on %s", l.PID, l.Hostname)
+}
+
+// Returns the lock of the book held by GnuCash or another process, nil if the
+// book isn't locked or is locked by this book
+func (b *Book) GetLock() (*Lock, error) {
+	// GnuCash creates the table with the book, but doesn't need it
+	if !b.hasTable("gnclock") {
+		return nil, nil
+	}
+
+	ls := []Lock{}
+	err := b.DB.Select(&ls, `SELECT COALESCE(Hostname, '') AS hostname, COALESCE(PID, 0) AS pid
+		FROM gnclock`)
+	if err != nil {
+		return nil, dbError(err, "loading gnclock")
+	}
+
+	for _, l := range ls {
+		if b.lock == nil || l != *b.lock {
+			return &l, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// Locks the book like GnuCash does while it has a book open, so that GnuCash
+// refuses to open it while we write. Returns ErrLocked if the book is locked by
+// GnuCash or another process unless force is set, in which case the book is
+// locked anyway and their lock kept. Locks are released by Unlock or Close.
+func (b *Book) Lock(force bool) error {
+	if b.lock != nil {
+		return nil
+	}
+
+	host, err := os.Hostname()
+	if err != nil {
+		return fmt.Errorf("locking book: %w", err)
+	}
+	lock := &Lock{Hostname: host, PID: os.Getpid()}
+
+	_, err = b.DB.Exec(`CREATE TABLE IF NOT EXISTS gnclock ( Hostname varchar(255), PID int )`)
+	if err != nil {
+		return dbError(err, "creating gnclock")
+	}
+
+	// Only lock if nobody else has (unless forced), in one statement so that
+	// nobody else can lock in between
+	cond := "NOT EXISTS (SELECT 1 FROM gnclock)"
+	if force {
+		cond = "1"
+	}
+	res, err := b.DB.Exec(`INSERT INTO gnclock (Hostname, PID) SELECT ?, ? WHERE `+cond,
+		lock.Hostname, lock.PID)
+	if err != nil {
+		return dbError(err, "locking book")
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return dbError(err, "locking book")
+	}
+	if n == 0 {
+		other, err := b.GetLock()
+		if err != nil {
+			return err
+		}
+		if other == nil {
+			return newError(ErrLocked, "book is locked")
+		}
+		return newError(ErrLocked, "book is locked by %s", other)
+	}
+
+	b.lock = lock
+
+	return nil
+}
+
+// Releases the lock taken by Lock, if any
+func (b *Book) Unlock() error {
+	if b.lock == nil {
+		return nil
+	}
+
+	_, err := b.DB.Exec(`DELETE FROM gnclock WHERE Hostname = ? AND PID = ?`,
+		b.lock.Hostname, b.lock.PID)
+	if err != nil {
+		return dbError(err, "unlocking book")
+	}
+
+	b.lock = nil
+
+	return nil
+}
+
+func (b *Book) hasTable(name string) bool {
+	var n int
+	err := b.DB.Get(&n, `SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?`, name)
+	return err == nil && n > 0
+}
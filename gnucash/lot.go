@@ -2,7 +2,7 @@ package gnucash
 
 import (
 	"database/sql"
-	"log"
+	"math/big"
 )
 
 type Lot struct {
@@ -16,14 +16,12 @@ type DbLot struct {
 	IsClosed    int            `db:"is_closed"`
 }
 
-func (l *Lot) write() {
+func (l *Lot) write() error {
 	query := `INSERT OR REPLACE INTO "lots" ("guid", "account_guid", "is_closed")
 		VALUES (:guid, :account_guid, :is_closed)`
 
 	_, err := l.book.DB.NamedExec(query, l.DbLot)
-	if err != nil {
-		log.Fatal(err)
-	}
+	return dbError(err, "writing lot %s", l.Guid)
 }
 
 func (l *Lot) GetAccount() *Account {
@@ -34,7 +32,7 @@ func (l *Lot) GetAccount() *Account {
 	return l.book.GetAccountByGUID(l.AccountGuid.String)
 }
 
-func (l *Lot) SetInvoice(i *Invoice) {
+func (l *Lot) SetInvoice(i *Invoice) error {
 	pSlot := l.book.getSlotForObjByName(l.Guid, "gncInvoice")
 	if pSlot == nil {
 		// If slot doesn't exist, create it
@@ -46,7 +44,9 @@ func (l *Lot) SetInvoice(i *Invoice) {
 				GuidVal:  sql.NullString{NewGuid(), true},
 			},
 		}
-		l.book.AddSlot(pSlot)
+		if err := l.book.AddSlot(pSlot); err != nil {
+			return err
+		}
 	}
 
 	var cSlot *Slot
@@ -66,9 +66,86 @@ func (l *Lot) SetInvoice(i *Invoice) {
 				GuidVal:  sql.NullString{i.Guid, true},
 			},
 		}
-		pSlot.AddChild(cSlot)
-	} else {
-		cSlot.GuidVal = sql.NullString{i.Guid, true}
-		cSlot.Write()
+		return pSlot.AddChild(cSlot)
+	}
+
+	cSlot.GuidVal = sql.NullString{i.Guid, true}
+	return cSlot.Write()
+}
+
+// Removes the link between lot and invoice
+func (l *Lot) removeInvoice() error {
+	if s := l.book.getSlotForObjByName(l.Guid, "gncInvoice"); s != nil {
+		return l.book.removeSlotTree(s)
+	}
+
+	return nil
+}
+
+// Attaches lot to an owner (e.g. a vendor) as GnuCash does for pre-payments
+func (l *Lot) SetOwner(ownerType OwnerType, ownerGuid string) error {
+	if _, err := l.book.SetSlotInt64(l.Guid, "gncOwner/owner-type", int64(ownerType)); err != nil {
+		return err
+	}
+
+	_, err := l.book.SetSlotGuid(l.Guid, "gncOwner/owner-guid", ownerGuid)
+	return err
+}
+
+// Returns all splits assigned to this lot
+func (l *Lot) GetSplits() []*Split {
+	guids := l.book.splitsByLot[l.Guid]
+	splits := make([]*Split, 0, len(guids))
+	for _, guid := range guids {
+		splits = append(splits, l.book.splitsByGuid[guid])
+	}
+
+	return splits
+}
+
+// Returns the balance of the lot, i.e. the sum of the amounts of its splits in
+// the lot account
+func (l *Lot) GetBalance() *big.Rat {
+	bal := big.NewRat(0, 1)
+	for _, s := range l.GetSplits() {
+		if s.AccountGuid != l.AccountGuid.String {
+			continue
+		}
+
+		bal.Add(bal, big.NewRat(s.QuantityNum, s.QuantityDenom))
+	}
+
+	return bal
+}
+
+// Recalculates the closed state of the lot (closed if its balance is zero) and
+// writes it
+func (l *Lot) updateClosed() error {
+	l.IsClosed = 0
+	if len(l.GetSplits()) > 0 && l.GetBalance().Sign() == 0 {
+		l.IsClosed = 1
+	}
+	return l.write()
+}
+
+// Removes lot and its slots
+func (l *Lot) remove() error {
+	_, err := l.book.DB.Exec(`DELETE FROM "lots" WHERE "guid"=?`, l.Guid)
+	if err != nil {
+		return dbError(err, "deleting lot %s", l.Guid)
+	}
+
+	if err := l.book.removeSlotsForObj(l.Guid); err != nil {
+		return err
+	}
+
+	delete(l.book.lotsByGuid, l.Guid)
+	for i, x := range l.book.lots {
+		if x == l {
+			l.book.lots = append(l.book.lots[:i], l.book.lots[i+1:]...)
+			break
+		}
 	}
+
+	return nil
 }
@@ -0,0 +1,118 @@
+package gnucash
+
+import (
+	"strings"
+	"time"
+)
+
+// Tables of a book that can be selected with LoadTables. Accounts and
+// commodities are always loaded.
+type Table int
+
+const (
+	TableSlots Table = 1 << iota
+	TableLots
+	TableVendors
+	TableCustomers
+	TableJobs
+	TableEmployees
+	TableTaxTables
+	TableBillTerms
+	TableInvoices     // invoices and their entries (requires slots and tax tables)
+	TableTransactions // transactions and their splits
+
+	TableAll = TableSlots | TableLots | TableVendors | TableCustomers | TableJobs |
+		TableEmployees | TableTaxTables | TableBillTerms | TableInvoices | TableTransactions
+)
+
+// Option of OpenBookFromSQLite. Without options the whole book is loaded.
+//
+// Books opened with options are partially loaded: they can be read and their
+// slots, vendors, customers, jobs, employees, tax tables and billing terms can
+// be changed, but invoices, transactions, splits and lots can't (ErrUnsupported).
+type OpenOption func(*openOptions)
+
+type openOptions struct {
+	tables  Table
+	account string // path of the account subtree
+	start   time.Time
+	end     time.Time
+	lazy    bool
+}
+
+// Loads only the given tables (e.g. TableSlots|TableVendors)
+func LoadTables(tables Table) OpenOption {
+	return func(o *openOptions) {
+		o.tables = tables
+	}
+}
+
+// Loads only the transactions with splits in the account at path (e.g.
+// "Liabilities:Accounts Payable") or its descendants, including all of their
+// splits
+func LoadAccountSubtree(path string) OpenOption {
+	return func(o *openOptions) {
+		o.account = path
+	}
+}
+
+// Loads only the transactions posted from start (inclusive) to end (exclusive).
+// A zero start or end leaves the range open on that side.
+func LoadDateRange(start time.Time, end time.Time) OpenOption {
+	return func(o *openOptions) {
+		o.start = start
+		o.end = end
+	}
+}
+
+// Loads transactions and entries on demand: the splits of an account (with
+// their transactions) by Account.GetSplits, the entries of an invoice by
+// Invoice.GetEntries. Lots only see the splits loaded so far.
+func LoadLazily() OpenOption {
+	return func(o *openOptions) {
+		o.lazy = true
+	}
+}
+
+func (o *openOptions) isComplete() bool {
+	return o.tables == TableAll && o.account == "" && o.start.IsZero() &&
+		o.end.IsZero() && !o.lazy
+}
+
+// Returns the condition on transactions (as t) selecting those in the date
+// range with splits in one of the given accounts (any account if nil)
+func (o *openOptions) transactionCondition(accounts []string) (string, []interface{}) {
+	conds := []string{"1"}
+	args := []interface{}{}
+
+	if !o.start.IsZero() {
+		conds = append(conds, "t.post_date >= ?")
+		args = append(args, o.start.UTC().Format("2006-01-02 15:04:05"))
+	}
+	if !o.end.IsZero() {
+		conds = append(conds, "t.post_date < ?")
+		args = append(args, o.end.UTC().Format("2006-01-02 15:04:05"))
+	}
+
+	if accounts != nil && len(accounts) == 0 {
+		conds = append(conds, "0")
+	} else if accounts != nil {
+		conds = append(conds, `t.guid IN (SELECT tx_guid FROM splits
+			WHERE account_guid IN (?`+strings.Repeat(", ?", len(accounts)-1)+`))`)
+		for _, a := range accounts {
+			args = append(args, a)
+		}
+	}
+
+	return strings.Join(conds, " AND "), args
+}
+
+// Returns ErrUnsupported if the book is only partially loaded, as op (e.g.
+// "posting invoice 000042") would need all of it
+func (b *Book) checkComplete(op string) error {
+	if !b.options.isComplete() {
+		return newError(ErrUnsupported, "%s in a partially loaded book", op)
+	}
+
+	return nil
+}
@@ -2,7 +2,7 @@ package gnucash
 
 import (
 	"database/sql"
-	"log"
+	"strings"
 )
 
 type Slot struct {
@@ -27,20 +27,29 @@ type DbSlot struct {
 
 // Same as write, but will not replace existing entry and lets sqlite assign id
 // automatically
-func (s *Slot) create() {
+func (s *Slot) create() error {
 	query := `INSERT INTO slots(obj_guid, name, slot_type,
 			int64_val, string_val, double_val, timespec_val, guid_val,
 			numeric_val_num, numeric_val_denom, gdate_val)
 		VALUES(:obj_guid, :name, :slot_type, :int64_val, :string_val,
 			:double_val, :timespec_val, :guid_val, :numeric_val_num,
 			:numeric_val_denom, :gdate_val)`
-	_, err := s.book.DB.NamedExec(query, s.DbSlot)
+	res, err := s.book.DB.NamedExec(query, s.DbSlot)
+	if err != nil {
+		return dbError(err, "creating slot %s of %s", s.Name, s.ObjGuid)
+	}
+
+	// Remember id so the slot can be written or removed later on
+	id, err := res.LastInsertId()
 	if err != nil {
-		log.Fatal(err)
+		return dbError(err, "creating slot %s of %s", s.Name, s.ObjGuid)
 	}
+	s.Id = int(id)
+
+	return nil
 }
 
-func (s *Slot) Write() {
+func (s *Slot) Write() error {
 	query := `INSERT OR REPLACE INTO slots(id, obj_guid, name, slot_type,
 			int64_val, string_val, double_val, timespec_val, guid_val,
 			numeric_val_num, numeric_val_denom, gdate_val)
@@ -48,32 +57,160 @@ func (s *Slot) Write() {
 			:double_val, :timespec_val, :guid_val, :numeric_val_num,
 			:numeric_val_denom, :gdate_val)`
 	_, err := s.book.DB.NamedExec(query, s.DbSlot)
-	if err != nil {
-		log.Fatal(err)
-	}
+	return dbError(err, "writing slot %s of %s", s.Name, s.ObjGuid)
 }
 
-func (s *Slot) remove() {
+func (s *Slot) remove() error {
 	query := `DELETE FROM slots WHERE id=:id`
 	_, err := s.book.DB.NamedExec(query, s.DbSlot)
-	if err != nil {
-		log.Fatal(err)
-	}
+	return dbError(err, "removing slot %s of %s", s.Name, s.ObjGuid)
 }
 
+// Returns the slots contained in a frame slot (none for other slots)
 func (s *Slot) GetChildren() []*Slot {
 	if !s.GuidVal.Valid {
-		log.Fatal("Cannot GetChildren() when GuidVal of slot is null")
+		return []*Slot{}
 	}
 
 	return s.book.getSlotsByObjGUID(s.GuidVal.String)
 }
 
-func (s *Slot) AddChild(c *Slot) {
+func (s *Slot) AddChild(c *Slot) error {
 	if !s.GuidVal.Valid {
-		log.Fatal("Cannot add child to slot if parent GuidVal is null")
+		return newError(ErrCorruptBook, "adding child to slot %s of %s without GUID value", s.Name, s.ObjGuid)
 	}
 
 	c.ObjGuid = s.GuidVal.String
-	s.book.AddSlot(c)
+	return s.book.AddSlot(c)
+}
+
+// Returns the slot at the given path (e.g. "gncInvoice/invoice-guid") of the
+// object with GUID objGuid, descending into frame slots as necessary. Returns
+// nil if no such slot exists.
+func (b *Book) GetSlot(objGuid string, path string) *Slot {
+	parts := strings.Split(path, "/")
+	guid := objGuid
+
+	var s *Slot
+	for i := range parts {
+		s = b.getSlotForObjByName(guid, strings.Join(parts[:i+1], "/"))
+		if s == nil {
+			return nil
+		}
+
+		if i < len(parts)-1 {
+			if s.SlotType != int(SlotTypeFrame) || !s.GuidVal.Valid {
+				return nil
+			}
+			guid = s.GuidVal.String
+		}
+	}
+
+	return s
+}
+
+// Creates all frame slots on the way to path (if they don't exist yet) and
+// returns the GUID that a slot at path has to use as its obj_guid
+func (b *Book) ensureSlotFrames(objGuid string, path string) (string, error) {
+	parts := strings.Split(path, "/")
+	guid := objGuid
+
+	for i := 0; i < len(parts)-1; i++ {
+		name := strings.Join(parts[:i+1], "/")
+		s := b.getSlotForObjByName(guid, name)
+		if s == nil {
+			s = &Slot{
+				DbSlot: DbSlot{
+					ObjGuid:  guid,
+					Name:     name,
+					SlotType: int(SlotTypeFrame),
+					GuidVal:  sql.NullString{NewGuid(), true},
+				},
+			}
+			if err := b.AddSlot(s); err != nil {
+				return "", err
+			}
+		} else if s.SlotType != int(SlotTypeFrame) || !s.GuidVal.Valid {
+			return "", newError(ErrCorruptBook, "slot %s of object %s is not a frame", name, objGuid)
+		}
+
+		guid = s.GuidVal.String
+	}
+
+	return guid, nil
+}
+
+// Creates or updates the slot at path of the object with GUID objGuid, taking
+// type and value from val
+func (b *Book) setSlot(objGuid string, path string, val *Slot) (*Slot, error) {
+	guid, err := b.ensureSlotFrames(objGuid, path)
+	if err != nil {
+		return nil, err
+	}
+
+	s := b.getSlotForObjByName(guid, path)
+	if s == nil {
+		val.ObjGuid = guid
+		val.Name = path
+		return val, b.AddSlot(val)
+	}
+
+	if s.SlotType != val.SlotType {
+		return nil, newError(ErrCorruptBook, "slot %s of object %s has type %d, expected %d",
+			path, objGuid, s.SlotType, val.SlotType)
+	}
+
+	s.Int64Val = val.Int64Val
+	s.StringVal = val.StringVal
+	s.GuidVal = val.GuidVal
+	s.TimespecVal = val.TimespecVal
+	return s, s.Write()
+}
+
+func (b *Book) SetSlotString(objGuid string, path string, val string) (*Slot, error) {
+	return b.setSlot(objGuid, path, &Slot{
+		DbSlot: DbSlot{
+			SlotType:  int(SlotTypeString),
+			StringVal: sql.NullString{val, true},
+		},
+	})
+}
+
+func (b *Book) SetSlotInt64(objGuid string, path string, val int64) (*Slot, error) {
+	return b.setSlot(objGuid, path, &Slot{
+		DbSlot: DbSlot{
+			SlotType: int(SlotTypeInt64),
+			Int64Val: sql.NullInt64{val, true},
+		},
+	})
+}
+
+func (b *Book) SetSlotGuid(objGuid string, path string, val string) (*Slot, error) {
+	return b.setSlot(objGuid, path, &Slot{
+		DbSlot: DbSlot{
+			SlotType: int(SlotTypeGuid),
+			GuidVal:  sql.NullString{val, true},
+		},
+	})
+}
+
+// Removes the slot at path of the object with GUID objGuid (including the
+// contents of frames), if it exists
+func (b *Book) DeleteSlot(objGuid string, path string) error {
+	if s := b.GetSlot(objGuid, path); s != nil {
+		return b.removeSlotTree(s)
+	}
+
+	return nil
+}
+
+// Removes a slot and, if it is a frame, all slots contained in it
+func (b *Book) removeSlotTree(s *Slot) error {
+	if s.SlotType == int(SlotTypeFrame) && s.GuidVal.Valid {
+		if err := b.removeSlotsForObj(s.GuidVal.String); err != nil {
+			return err
+		}
+	}
+
+	return b.RemoveSlot(s)
 }
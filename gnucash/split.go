@@ -2,7 +2,7 @@ package gnucash
 
 import (
 	"database/sql"
-	"log"
+	"math/big"
 )
 
 type Split struct {
@@ -10,6 +10,8 @@ type Split struct {
 	DbSplit
 	Account     *Account
 	Transaction *Transaction
+
+	indexedLot string // lot GUID the split is indexed under, see indexLot
 }
 
 type DbSplit struct {
@@ -27,7 +29,7 @@ type DbSplit struct {
 	LotGuid        sql.NullString `db:"lot_guid"`
 }
 
-func (s *Split) Write() {
+func (s *Split) Write() error {
 	query := `INSERT OR REPLACE INTO "splits" ("guid", "tx_guid",
 			"account_guid", "memo", "action", "reconcile_state",
 			"reconcile_date", "value_num", "value_denom", "quantity_num",
@@ -37,6 +39,212 @@ func (s *Split) Write() {
 			:quantity_num, :quantity_denom, :lot_guid)`
 	_, err := s.book.DB.NamedExec(query, s.DbSplit)
 	if err != nil {
-		log.Fatal(err)
+		return dbError(err, "writing split %s", s.Guid)
+	}
+
+	s.indexLot()
+	return nil
+}
+
+// Updates the book's index of splits by lot
+func (s *Split) indexLot() {
+	lot := ""
+	if s.LotGuid.Valid {
+		lot = s.LotGuid.String
+	}
+
+	if lot == s.indexedLot {
+		return
+	}
+	if s.indexedLot != "" {
+		s.book.splitsByLot.remove(s.indexedLot, s.Guid)
+	}
+	if lot != "" {
+		s.book.splitsByLot.add(lot, s.Guid)
+	}
+	s.indexedLot = lot
+}
+
+// Removes split from the database and from its account and transaction
+func (s *Split) remove() error {
+	_, err := s.book.DB.Exec(`DELETE FROM "splits" WHERE "guid"=?`, s.Guid)
+	if err != nil {
+		return dbError(err, "deleting split %s", s.Guid)
+	}
+
+	if err := s.book.removeSlotsForObj(s.Guid); err != nil {
+		return err
+	}
+
+	delete(s.book.splitsByGuid, s.Guid)
+	if s.indexedLot != "" {
+		s.book.splitsByLot.remove(s.indexedLot, s.Guid)
+		s.indexedLot = ""
 	}
+	for i, x := range s.book.splits {
+		if x == s {
+			s.book.splits = append(s.book.splits[:i], s.book.splits[i+1:]...)
+			break
+		}
+	}
+
+	if s.Account != nil {
+		for i, x := range s.Account.Splits {
+			if x == s {
+				s.Account.Splits = append(s.Account.Splits[:i], s.Account.Splits[i+1:]...)
+				break
+			}
+		}
+	}
+
+	if s.Transaction != nil {
+		for i, x := range s.Transaction.Splits {
+			if x == s {
+				s.Transaction.Splits = append(s.Transaction.Splits[:i], s.Transaction.Splits[i+1:]...)
+				break
+			}
+		}
+	}
+
+	return nil
+}
+
+// Moves split to account a
+func (s *Split) SetAccount(a *Account) error {
+	if err := s.book.checkComplete("moving split " + s.Guid); err != nil {
+		return err
+	}
+
+	if s.Account != nil {
+		for i, x := range s.Account.Splits {
+			if x == s {
+				s.Account.Splits = append(s.Account.Splits[:i], s.Account.Splits[i+1:]...)
+				break
+			}
+		}
+	}
+
+	s.Account = a
+	s.AccountGuid = a.Guid
+	a.Splits = append(a.Splits, s)
+	return s.Write()
+}
+
+// Reduces the amount of the split by amount and moves it to a new split of the
+// same transaction and account, which is returned. The value is reduced
+// proportionally, so the transaction stays balanced.
+func (s *Split) splitOff(amount *big.Rat) (*Split, error) {
+	value := new(big.Rat).Mul(amount, big.NewRat(s.ValueNum, s.QuantityNum))
+	value.Mul(value, big.NewRat(s.QuantityDenom, s.ValueDenom))
+
+	n := &Split{
+		Account: s.Account,
+		DbSplit: DbSplit{
+			Memo:           s.Memo,
+			Action:         s.Action,
+			ReconcileState: s.ReconcileState,
+			ReconcileDate:  s.ReconcileDate,
+			ValueNum:       value.Num().Int64(),
+			ValueDenom:     value.Denom().Int64(),
+			QuantityNum:    amount.Num().Int64(),
+			QuantityDenom:  amount.Denom().Int64(),
+		},
+	}
+
+	if err := s.addAmount(new(big.Rat).Neg(value), new(big.Rat).Neg(amount)); err != nil {
+		return nil, err
+	}
+	if err := s.Transaction.AddSplit(n); err != nil {
+		return nil, err
+	}
+
+	return n, nil
+}
+
+// Merges split o (of the same transaction and account) back into s and removes
+// it, undoing splitOff. A lot left empty by this is removed as well.
+func (s *Split) Join(o *Split) error {
+	if err := s.book.checkComplete("joining split " + o.Guid); err != nil {
+		return err
+	}
+
+	if o.TxGuid != s.TxGuid || o.AccountGuid != s.AccountGuid {
+		return newError(ErrConstraint, "can't join split %s into split %s of a different transaction or account",
+			o.Guid, s.Guid)
+	}
+
+	err := s.addAmount(big.NewRat(o.ValueNum, o.ValueDenom),
+		big.NewRat(o.QuantityNum, o.QuantityDenom))
+	if err != nil {
+		return err
+	}
+	if err := o.remove(); err != nil {
+		return err
+	}
+
+	if s.LotGuid.Valid {
+		if lot := s.book.GetLotByGUID(s.LotGuid.String); lot != nil {
+			if err := lot.updateClosed(); err != nil {
+				return err
+			}
+		}
+	}
+
+	if o.LotGuid.Valid {
+		lot := s.book.GetLotByGUID(o.LotGuid.String)
+		if lot != nil && len(lot.GetSplits()) == 0 {
+			return lot.remove()
+		}
+	}
+
+	return nil
+}
+
+// Moves the split to lot (nil for none) and writes it, updating the closed
+// state of both lots. A lot left without splits (e.g. the pre-payment lot of an
+// unassigned overpayment) is removed.
+func (s *Split) SetLot(lot *Lot) error {
+	if err := s.book.checkComplete("moving split " + s.Guid + " to another lot"); err != nil {
+		return err
+	}
+
+	var old *Lot
+	if s.LotGuid.Valid && s.LotGuid.String != "" {
+		old = s.book.GetLotByGUID(s.LotGuid.String)
+	}
+
+	s.LotGuid = sql.NullString{}
+	if lot != nil {
+		s.LotGuid = sql.NullString{lot.Guid, true}
+	}
+	if err := s.Write(); err != nil {
+		return err
+	}
+
+	if lot != nil {
+		if err := lot.updateClosed(); err != nil {
+			return err
+		}
+	}
+
+	if old != nil && old != lot {
+		if len(old.GetSplits()) == 0 {
+			return old.remove()
+		}
+		return old.updateClosed()
+	}
+
+	return nil
+}
+
+// Adds value and amount to the split and writes it
+func (s *Split) addAmount(value *big.Rat, amount *big.Rat) error {
+	value.Add(value, big.NewRat(s.ValueNum, s.ValueDenom))
+	amount.Add(amount, big.NewRat(s.QuantityNum, s.QuantityDenom))
+
+	s.ValueNum = value.Num().Int64()
+	s.ValueDenom = value.Denom().Int64()
+	s.QuantityNum = amount.Num().Int64()
+	s.QuantityDenom = amount.Denom().Int64()
+	return s.Write()
 }
@@ -0,0 +1,122 @@
+package gnucash
+
+import (
+	"database/sql"
+	"math/big"
+)
+
+type TaxTable struct {
+	book *Book
+	DbTaxTable
+	Entries []*TaxTableEntry
+
+	indexed indexedKeys // keys of the book's indexes, see index
+}
+
+type DbTaxTable struct {
+	Guid      string
+	Name      string
+	Refcount  int64
+	Invisible int
+	Parent    sql.NullString
+}
+
+type TaxTableEntry struct {
+	book *Book
+	DbTaxTableEntry
+	Account *Account
+}
+
+type DbTaxTableEntry struct {
+	Id          int64
+	TaxTable    string `db:"taxtable"`
+	AccountGuid string `db:"account"`
+	AmountNum   int64  `db:"amount_num"`
+	AmountDenom int64  `db:"amount_denom"`
+	Type        int
+}
+
+// Tax amount of an entry (or invoice) booked to a tax account
+type Tax struct {
+	Account *Account
+	Amount  *big.Rat
+}
+
+func (t *TaxTable) write() error {
+	query := `INSERT OR REPLACE INTO "taxtables" ("guid", "name", "refcount",
+			"invisible", "parent")
+		VALUES (:guid, :name, :refcount, :invisible, :parent)`
+
+	_, err := t.book.DB.NamedExec(query, t.DbTaxTable)
+	if err != nil {
+		return dbError(err, "writing tax table %s", t.Name)
+	}
+
+	t.index()
+	return nil
+}
+
+// Updates the book's index by name
+func (t *TaxTable) index() {
+	t.book.taxTablesByName.update(t.Guid, t.indexed.ok, t.indexed.name, t.Name)
+	t.indexed = indexedKeys{ok: true, name: t.Name}
+}
+
+func (te *TaxTableEntry) create() error {
+	query := `INSERT INTO "taxtable_entries" ("taxtable", "account",
+			"amount_num", "amount_denom", "type")
+		VALUES (:taxtable, :account, :amount_num, :amount_denom, :type)`
+
+	res, err := te.book.DB.NamedExec(query, te.DbTaxTableEntry)
+	if err != nil {
+		return dbError(err, "creating entry of tax table %s", te.TaxTable)
+	}
+
+	te.Id, err = res.LastInsertId()
+	return dbError(err, "creating entry of tax table %s", te.TaxTable)
+}
+
+// Adds a tax booked to account acc to the table. Amount is a percentage of the
+// net value (e.g. 19 for 19% VAT) or a fixed value per entry, depending on tp.
+func (t *TaxTable) AddEntry(acc *Account, amount *big.Rat, tp AmountType) (*TaxTableEntry, error) {
+	te := &TaxTableEntry{
+		book:    t.book,
+		Account: acc,
+		DbTaxTableEntry: DbTaxTableEntry{
+			TaxTable:    t.Guid,
+			AccountGuid: acc.Guid,
+			AmountNum:   amount.Num().Int64(),
+			AmountDenom: amount.Denom().Int64(),
+			Type:        int(tp),
+		},
+	}
+	if err := te.create(); err != nil {
+		return nil, err
+	}
+	t.Entries = append(t.Entries, te)
+
+	return te, nil
+}
+
+func (te *TaxTableEntry) GetAmount() *big.Rat {
+	return big.NewRat(te.AmountNum, te.AmountDenom)
+}
+
+// Returns the sum of all percentages and the sum of all fixed values of the
+// table
+func (t *TaxTable) getRates() (percent *big.Rat, value *big.Rat, err error) {
+	percent = new(big.Rat)
+	value = new(big.Rat)
+	for _, te := range t.Entries {
+		switch AmountType(te.Type) {
+		case AmountTypePercent:
+			percent.Add(percent, te.GetAmount())
+		case AmountTypeValue:
+			value.Add(value, te.GetAmount())
+		default:
+			return nil, nil, newError(ErrUnsupported, "tax table %s has an entry of type %d", t.Name, te.Type)
+		}
+	}
+
+	return percent, value, nil
+}
@@ -0,0 +1,15 @@
+-- Book with EUR as default currency, the usual accounts and the last bill
+-- number issued stored in a counters frame like GnuCash does
+INSERT INTO books VALUES('book0000000000000000000000000001','root0000000000000000000000000001','tmpl0000000000000000000000000001');
+INSERT INTO commodities VALUES('eur00000000000000000000000000001','CURRENCY','EUR','Euro','978',100,1,'currency',NULL);
+INSERT INTO accounts VALUES('root0000000000000000000000000001','Root Account','ROOT','eur00000000000000000000000000001',100,0,NULL,'','',0,0);
+INSERT INTO accounts VALUES('tmpl0000000000000000000000000001','Template Root','ROOT',NULL,0,0,NULL,'','',0,0);
+INSERT INTO accounts VALUES('asst0000000000000000000000000001','Assets','ASSET','eur00000000000000000000000000001',100,0,'root0000000000000000000000000001','','',0,1);
+INSERT INTO accounts VALUES('bank0000000000000000000000000001','Bank','BANK','eur00000000000000000000000000001',100,0,'asst0000000000000000000000000001','','',0,0);
+INSERT INTO accounts VALUES('ar000000000000000000000000000001','Accounts Receivable','RECEIVABLE','eur00000000000000000000000000001',100,0,'asst0000000000000000000000000001','','',0,0);
+INSERT INTO accounts VALUES('liab0000000000000000000000000001','Liabilities','LIABILITY','eur00000000000000000000000000001',100,0,'root0000000000000000000000000001','','',0,1);
+INSERT INTO accounts VALUES('ap000000000000000000000000000001','Accounts Payable','PAYABLE','eur00000000000000000000000000001',100,0,'liab0000000000000000000000000001','','',0,0);
+INSERT INTO accounts VALUES('expn0000000000000000000000000001','Expenses','EXPENSE','eur00000000000000000000000000001',100,0,'root0000000000000000000000000001','','',0,0);
+INSERT INTO accounts VALUES('inc00000000000000000000000000001','Income','INCOME','eur00000000000000000000000000001',100,0,'root0000000000000000000000000001','','',0,0);
+INSERT INTO slots(obj_guid,name,slot_type,guid_val) VALUES('book0000000000000000000000000001','counters',9,'cntr0000000000000000000000000001');
+INSERT INTO slots(obj_guid,name,slot_type,int64_val) VALUES('cntr0000000000000000000000000001','counters/gncBill',1,5);
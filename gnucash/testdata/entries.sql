@@ -0,0 +1,173 @@
+-- Posted customer invoices and a vendor bill, one entry each, covering the
+-- discount types and applications, tax-included prices, credit notes and
+-- currencies with other fractions. The post transaction of each document holds
+-- the net value per income or expense account, the taxes per tax account and
+-- the gross value on A/R or A/P, as written when the document is posted.
+INSERT INTO books VALUES('book0000000000000000000000000001','root0000000000000000000000000001','tmpl0000000000000000000000000001');
+INSERT INTO commodities VALUES('eur00000000000000000000000000001','CURRENCY','EUR','Euro','978',100,1,'currency',NULL);
+INSERT INTO commodities VALUES('jpy00000000000000000000000000001','CURRENCY','JPY','Yen','392',1,1,'currency',NULL);
+INSERT INTO commodities VALUES('bhd00000000000000000000000000001','CURRENCY','BHD','Bahraini Dinar','048',1000,1,'currency',NULL);
+INSERT INTO accounts VALUES('root0000000000000000000000000001','Root Account','ROOT','eur00000000000000000000000000001',100,0,NULL,'','',0,0);
+INSERT INTO accounts VALUES('tmpl0000000000000000000000000001','Template Root','ROOT',NULL,0,0,NULL,'','',0,0);
+INSERT INTO accounts VALUES('areur000000000000000000000000001','Accounts Receivable EUR','RECEIVABLE','eur00000000000000000000000000001',100,0,'root0000000000000000000000000001','','',0,0);
+INSERT INTO accounts VALUES('inceur00000000000000000000000001','Income EUR','INCOME','eur00000000000000000000000000001',100,0,'root0000000000000000000000000001','','',0,0);
+INSERT INTO accounts VALUES('vateur00000000000000000000000001','VAT EUR','LIABILITY','eur00000000000000000000000000001',100,0,'root0000000000000000000000000001','','',0,0);
+INSERT INTO accounts VALUES('arjpy000000000000000000000000001','Accounts Receivable JPY','RECEIVABLE','jpy00000000000000000000000000001',1,0,'root0000000000000000000000000001','','',0,0);
+INSERT INTO accounts VALUES('incjpy00000000000000000000000001','Income JPY','INCOME','jpy00000000000000000000000000001',1,0,'root0000000000000000000000000001','','',0,0);
+INSERT INTO accounts VALUES('vatjpy00000000000000000000000001','VAT JPY','LIABILITY','jpy00000000000000000000000000001',1,0,'root0000000000000000000000000001','','',0,0);
+INSERT INTO accounts VALUES('arbhd000000000000000000000000001','Accounts Receivable BHD','RECEIVABLE','bhd00000000000000000000000000001',1000,0,'root0000000000000000000000000001','','',0,0);
+INSERT INTO accounts VALUES('incbhd00000000000000000000000001','Income BHD','INCOME','bhd00000000000000000000000000001',1000,0,'root0000000000000000000000000001','','',0,0);
+INSERT INTO accounts VALUES('vatbhd00000000000000000000000001','VAT BHD','LIABILITY','bhd00000000000000000000000000001',1000,0,'root0000000000000000000000000001','','',0,0);
+INSERT INTO accounts VALUES('vatr0000000000000000000000000001','VAT reduced','LIABILITY','eur00000000000000000000000000001',100,0,'root0000000000000000000000000001','','',0,0);
+INSERT INTO accounts VALUES('fee00000000000000000000000000001','Deposit','LIABILITY','eur00000000000000000000000000001',100,0,'root0000000000000000000000000001','','',0,0);
+INSERT INTO accounts VALUES('ap000000000000000000000000000001','Accounts Payable','PAYABLE','eur00000000000000000000000000001',100,0,'root0000000000000000000000000001','','',0,0);
+INSERT INTO accounts VALUES('exp00000000000000000000000000001','Expenses','EXPENSE','eur00000000000000000000000000001',100,0,'root0000000000000000000000000001','','',0,0);
+INSERT INTO accounts VALUES('vatin000000000000000000000000001','Input VAT','ASSET','eur00000000000000000000000000001',100,0,'root0000000000000000000000000001','','',0,0);
+INSERT INTO taxtables VALUES('tta00000000000000000000000000001','VAT 19%',1,0,NULL);
+INSERT INTO taxtable_entries(taxtable,account,amount_num,amount_denom,type) VALUES('tta00000000000000000000000000001','vateur00000000000000000000000001',19,1,2);
+INSERT INTO taxtables VALUES('ttb00000000000000000000000000001','VAT 19% + 7%',1,0,NULL);
+INSERT INTO taxtable_entries(taxtable,account,amount_num,amount_denom,type) VALUES('ttb00000000000000000000000000001','vateur00000000000000000000000001',19,1,2);
+INSERT INTO taxtable_entries(taxtable,account,amount_num,amount_denom,type) VALUES('ttb00000000000000000000000000001','vatr0000000000000000000000000001',7,1,2);
+INSERT INTO taxtables VALUES('ttc00000000000000000000000000001','VAT 19% + deposit',1,0,NULL);
+INSERT INTO taxtable_entries(taxtable,account,amount_num,amount_denom,type) VALUES('ttc00000000000000000000000000001','vateur00000000000000000000000001',19,1,2);
+INSERT INTO taxtable_entries(taxtable,account,amount_num,amount_denom,type) VALUES('ttc00000000000000000000000000001','fee00000000000000000000000000001',1,2,1);
+INSERT INTO taxtables VALUES('ttd00000000000000000000000000001','VAT 8%',1,0,NULL);
+INSERT INTO taxtable_entries(taxtable,account,amount_num,amount_denom,type) VALUES('ttd00000000000000000000000000001','vatjpy00000000000000000000000001',8,1,2);
+INSERT INTO taxtables VALUES('tte00000000000000000000000000001','VAT 5%',1,0,NULL);
+INSERT INTO taxtable_entries(taxtable,account,amount_num,amount_denom,type) VALUES('tte00000000000000000000000000001','vatbhd00000000000000000000000001',5,1,2);
+INSERT INTO taxtables VALUES('ttf00000000000000000000000000001','Input VAT 19%',1,0,NULL);
+INSERT INTO taxtable_entries(taxtable,account,amount_num,amount_denom,type) VALUES('ttf00000000000000000000000000001','vatin000000000000000000000000001',19,1,2);
+INSERT INTO customers VALUES('cust0000000000000000000000000001','ACME','000001','',1,0,1,0,1,'eur00000000000000000000000000001',0,'ACME Ltd','','','','','','','','','','','','','','','',NULL,NULL,NULL);
+INSERT INTO vendors VALUES('vend0000000000000000000000000001','SWM','000001','','eur00000000000000000000000000001',1,0,'SWM','','','','','','','',NULL,'1',NULL);
+
+-- Invoice 000001: Percent discount before taxes
+INSERT INTO invoices VALUES('inv00000000000000000000000000001','000001','2021-10-01 10:59:00','2021-10-01 10:59:00','',1,'eur00000000000000000000000000001',2,'cust0000000000000000000000000001',NULL,'','tx000000000000000000000000000001','lot00000000000000000000000000001','areur000000000000000000000000001',0,NULL,0,1);
+INSERT INTO slots(obj_guid,name,slot_type,int64_val) VALUES('inv00000000000000000000000000001','credit-note',1,0);
+INSERT INTO entries VALUES('ent00000000000000000000000000001','2021-10-01 10:59:00','2021-10-01 10:59:00','Percent discount before taxes','','',2,1,'inceur00000000000000000000000001',2499,100,10,1,'inv00000000000000000000000000001','PERCENT','PRETAX',1,0,'tta00000000000000000000000000001',NULL,NULL,NULL,NULL,NULL,NULL,NULL,NULL,0,0,NULL,NULL);
+INSERT INTO transactions VALUES('tx000000000000000000000000000001','eur00000000000000000000000000001','000001','2021-10-01 10:59:00','2021-10-01 10:59:00','ACME');
+INSERT INTO splits VALUES('sp1_0000000000000000000000000000','tx000000000000000000000000000001','areur000000000000000000000000001','','Invoice','n',NULL,5353,100,5353,100,'lot00000000000000000000000000001');
+INSERT INTO splits VALUES('sp1_0000000000000000000000000001','tx000000000000000000000000000001','inceur00000000000000000000000001','Percent discount before taxes','','n',NULL,-4498,100,-4498,100,NULL);
+INSERT INTO splits VALUES('sp1_0000000000000000000000000002','tx000000000000000000000000000001','vateur00000000000000000000000001','','','n',NULL,-855,100,-855,100,NULL);
+INSERT INTO lots VALUES('lot00000000000000000000000000001','areur000000000000000000000000001',0);
+
+-- Invoice 000002: Percent discount with taxes
+INSERT INTO invoices VALUES('inv00000000000000000000000000002','000002','2021-10-02 10:59:00','2021-10-02 10:59:00','',1,'eur00000000000000000000000000001',2,'cust0000000000000000000000000001',NULL,'','tx000000000000000000000000000002','lot00000000000000000000000000002','areur000000000000000000000000001',0,NULL,0,1);
+INSERT INTO slots(obj_guid,name,slot_type,int64_val) VALUES('inv00000000000000000000000000002','credit-note',1,0);
+INSERT INTO entries VALUES('ent00000000000000000000000000002','2021-10-02 10:59:00','2021-10-02 10:59:00','Percent discount with taxes','','',1,1,'inceur00000000000000000000000001',100,1,10,1,'inv00000000000000000000000000002','PERCENT','SAMETIME',1,0,'tta00000000000000000000000000001',NULL,NULL,NULL,NULL,NULL,NULL,NULL,NULL,0,0,NULL,NULL);
+INSERT INTO transactions VALUES('tx000000000000000000000000000002','eur00000000000000000000000000001','000002','2021-10-02 10:59:00','2021-10-02 10:59:00','ACME');
+INSERT INTO splits VALUES('sp2_0000000000000000000000000000','tx000000000000000000000000000002','areur000000000000000000000000001','','Invoice','n',NULL,10900,100,10900,100,'lot00000000000000000000000000002');
+INSERT INTO splits VALUES('sp2_0000000000000000000000000001','tx000000000000000000000000000002','inceur00000000000000000000000001','Percent discount with taxes','','n',NULL,-9000,100,-9000,100,NULL);
+INSERT INTO splits VALUES('sp2_0000000000000000000000000002','tx000000000000000000000000000002','vateur00000000000000000000000001','','','n',NULL,-1900,100,-1900,100,NULL);
+INSERT INTO lots VALUES('lot00000000000000000000000000002','areur000000000000000000000000001',0);
+
+-- Invoice 000003: Percent discount after taxes
+INSERT INTO invoices VALUES('inv00000000000000000000000000003','000003','2021-10-03 10:59:00','2021-10-03 10:59:00','',1,'eur00000000000000000000000000001',2,'cust0000000000000000000000000001',NULL,'','tx000000000000000000000000000003','lot00000000000000000000000000003','areur000000000000000000000000001',0,NULL,0,1);
+INSERT INTO slots(obj_guid,name,slot_type,int64_val) VALUES('inv00000000000000000000000000003','credit-note',1,0);
+INSERT INTO entries VALUES('ent00000000000000000000000000003','2021-10-03 10:59:00','2021-10-03 10:59:00','Percent discount after taxes','','',1,1,'inceur00000000000000000000000001',100,1,10,1,'inv00000000000000000000000000003','PERCENT','POSTTAX',1,0,'tta00000000000000000000000000001',NULL,NULL,NULL,NULL,NULL,NULL,NULL,NULL,0,0,NULL,NULL);
+INSERT INTO transactions VALUES('tx000000000000000000000000000003','eur00000000000000000000000000001','000003','2021-10-03 10:59:00','2021-10-03 10:59:00','ACME');
+INSERT INTO splits VALUES('sp3_0000000000000000000000000000','tx000000000000000000000000000003','areur000000000000000000000000001','','Invoice','n',NULL,10710,100,10710,100,'lot00000000000000000000000000003');
+INSERT INTO splits VALUES('sp3_0000000000000000000000000001','tx000000000000000000000000000003','inceur00000000000000000000000001','Percent discount after taxes','','n',NULL,-8810,100,-8810,100,NULL);
+INSERT INTO splits VALUES('sp3_0000000000000000000000000002','tx000000000000000000000000000003','vateur00000000000000000000000001','','','n',NULL,-1900,100,-1900,100,NULL);
+INSERT INTO lots VALUES('lot00000000000000000000000000003','areur000000000000000000000000001',0);
+
+-- Invoice 000004: Value discount before taxes
+INSERT INTO invoices VALUES('inv00000000000000000000000000004','000004','2021-10-04 10:59:00','2021-10-04 10:59:00','',1,'eur00000000000000000000000000001',2,'cust0000000000000000000000000001',NULL,'','tx000000000000000000000000000004','lot00000000000000000000000000004','areur000000000000000000000000001',0,NULL,0,1);
+INSERT INTO slots(obj_guid,name,slot_type,int64_val) VALUES('inv00000000000000000000000000004','credit-note',1,0);
+INSERT INTO entries VALUES('ent00000000000000000000000000004','2021-10-04 10:59:00','2021-10-04 10:59:00','Value discount before taxes','','',1,1,'inceur00000000000000000000000001',100,1,5,1,'inv00000000000000000000000000004','VALUE','PRETAX',1,0,'tta00000000000000000000000000001',NULL,NULL,NULL,NULL,NULL,NULL,NULL,NULL,0,0,NULL,NULL);
+INSERT INTO transactions VALUES('tx000000000000000000000000000004','eur00000000000000000000000000001','000004','2021-10-04 10:59:00','2021-10-04 10:59:00','ACME');
+INSERT INTO splits VALUES('sp4_0000000000000000000000000000','tx000000000000000000000000000004','areur000000000000000000000000001','','Invoice','n',NULL,11305,100,11305,100,'lot00000000000000000000000000004');
+INSERT INTO splits VALUES('sp4_0000000000000000000000000001','tx000000000000000000000000000004','inceur00000000000000000000000001','Value discount before taxes','','n',NULL,-9500,100,-9500,100,NULL);
+INSERT INTO splits VALUES('sp4_0000000000000000000000000002','tx000000000000000000000000000004','vateur00000000000000000000000001','','','n',NULL,-1805,100,-1805,100,NULL);
+INSERT INTO lots VALUES('lot00000000000000000000000000004','areur000000000000000000000000001',0);
+
+-- Invoice 000005: Value discount with taxes
+INSERT INTO invoices VALUES('inv00000000000000000000000000005','000005','2021-10-05 10:59:00','2021-10-05 10:59:00','',1,'eur00000000000000000000000000001',2,'cust0000000000000000000000000001',NULL,'','tx000000000000000000000000000005','lot00000000000000000000000000005','areur000000000000000000000000001',0,NULL,0,1);
+INSERT INTO slots(obj_guid,name,slot_type,int64_val) VALUES('inv00000000000000000000000000005','credit-note',1,0);
+INSERT INTO entries VALUES('ent00000000000000000000000000005','2021-10-05 10:59:00','2021-10-05 10:59:00','Value discount with taxes','','',1,1,'inceur00000000000000000000000001',100,1,5,1,'inv00000000000000000000000000005','VALUE','SAMETIME',1,0,'tta00000000000000000000000000001',NULL,NULL,NULL,NULL,NULL,NULL,NULL,NULL,0,0,NULL,NULL);
+INSERT INTO transactions VALUES('tx000000000000000000000000000005','eur00000000000000000000000000001','000005','2021-10-05 10:59:00','2021-10-05 10:59:00','ACME');
+INSERT INTO splits VALUES('sp5_0000000000000000000000000000','tx000000000000000000000000000005','areur000000000000000000000000001','','Invoice','n',NULL,11400,100,11400,100,'lot00000000000000000000000000005');
+INSERT INTO splits VALUES('sp5_0000000000000000000000000001','tx000000000000000000000000000005','inceur00000000000000000000000001','Value discount with taxes','','n',NULL,-9500,100,-9500,100,NULL);
+INSERT INTO splits VALUES('sp5_0000000000000000000000000002','tx000000000000000000000000000005','vateur00000000000000000000000001','','','n',NULL,-1900,100,-1900,100,NULL);
+INSERT INTO lots VALUES('lot00000000000000000000000000005','areur000000000000000000000000001',0);
+
+-- Invoice 000006: Value discount after taxes
+INSERT INTO invoices VALUES('inv00000000000000000000000000006','000006','2021-10-06 10:59:00','2021-10-06 10:59:00','',1,'eur00000000000000000000000000001',2,'cust0000000000000000000000000001',NULL,'','tx000000000000000000000000000006','lot00000000000000000000000000006','areur000000000000000000000000001',0,NULL,0,1);
+INSERT INTO slots(obj_guid,name,slot_type,int64_val) VALUES('inv00000000000000000000000000006','credit-note',1,0);
+INSERT INTO entries VALUES('ent00000000000000000000000000006','2021-10-06 10:59:00','2021-10-06 10:59:00','Value discount after taxes','','',1,1,'inceur00000000000000000000000001',100,1,5,1,'inv00000000000000000000000000006','VALUE','POSTTAX',1,0,'tta00000000000000000000000000001',NULL,NULL,NULL,NULL,NULL,NULL,NULL,NULL,0,0,NULL,NULL);
+INSERT INTO transactions VALUES('tx000000000000000000000000000006','eur00000000000000000000000000001','000006','2021-10-06 10:59:00','2021-10-06 10:59:00','ACME');
+INSERT INTO splits VALUES('sp6_0000000000000000000000000000','tx000000000000000000000000000006','areur000000000000000000000000001','','Invoice','n',NULL,11400,100,11400,100,'lot00000000000000000000000000006');
+INSERT INTO splits VALUES('sp6_0000000000000000000000000001','tx000000000000000000000000000006','inceur00000000000000000000000001','Value discount after taxes','','n',NULL,-9500,100,-9500,100,NULL);
+INSERT INTO splits VALUES('sp6_0000000000000000000000000002','tx000000000000000000000000000006','vateur00000000000000000000000001','','','n',NULL,-1900,100,-1900,100,NULL);
+INSERT INTO lots VALUES('lot00000000000000000000000000006','areur000000000000000000000000001',0);
+
+-- Invoice 000007: Price including tax and deposit
+INSERT INTO invoices VALUES('inv00000000000000000000000000007','000007','2021-10-07 10:59:00','2021-10-07 10:59:00','',1,'eur00000000000000000000000000001',2,'cust0000000000000000000000000001',NULL,'','tx000000000000000000000000000007','lot00000000000000000000000000007','areur000000000000000000000000001',0,NULL,0,1);
+INSERT INTO slots(obj_guid,name,slot_type,int64_val) VALUES('inv00000000000000000000000000007','credit-note',1,0);
+INSERT INTO entries VALUES('ent00000000000000000000000000007','2021-10-07 10:59:00','2021-10-07 10:59:00','Price including tax and deposit','','',1,1,'inceur00000000000000000000000001',10,1,0,1,'inv00000000000000000000000000007','PERCENT','PRETAX',1,1,'ttc00000000000000000000000000001',NULL,NULL,NULL,NULL,NULL,NULL,NULL,NULL,0,0,NULL,NULL);
+INSERT INTO transactions VALUES('tx000000000000000000000000000007','eur00000000000000000000000000001','000007','2021-10-07 10:59:00','2021-10-07 10:59:00','ACME');
+INSERT INTO splits VALUES('sp7_0000000000000000000000000000','tx000000000000000000000000000007','areur000000000000000000000000001','','Invoice','n',NULL,1000,100,1000,100,'lot00000000000000000000000000007');
+INSERT INTO splits VALUES('sp7_0000000000000000000000000001','tx000000000000000000000000000007','inceur00000000000000000000000001','Price including tax and deposit','','n',NULL,-798,100,-798,100,NULL);
+INSERT INTO splits VALUES('sp7_0000000000000000000000000002','tx000000000000000000000000000007','vateur00000000000000000000000001','','','n',NULL,-152,100,-152,100,NULL);
+INSERT INTO splits VALUES('sp7_0000000000000000000000000003','tx000000000000000000000000000007','fee00000000000000000000000000001','','','n',NULL,-50,100,-50,100,NULL);
+INSERT INTO lots VALUES('lot00000000000000000000000000007','areur000000000000000000000000001',0);
+
+-- Invoice 000008: Price including two tax rates
+INSERT INTO invoices VALUES('inv00000000000000000000000000008','000008','2021-10-08 10:59:00','2021-10-08 10:59:00','',1,'eur00000000000000000000000000001',2,'cust0000000000000000000000000001',NULL,'','tx000000000000000000000000000008','lot00000000000000000000000000008','areur000000000000000000000000001',0,NULL,0,1);
+INSERT INTO slots(obj_guid,name,slot_type,int64_val) VALUES('inv00000000000000000000000000008','credit-note',1,0);
+INSERT INTO entries VALUES('ent00000000000000000000000000008','2021-10-08 10:59:00','2021-10-08 10:59:00','Price including two tax rates','','',1,1,'inceur00000000000000000000000001',10,1,0,1,'inv00000000000000000000000000008','PERCENT','PRETAX',1,1,'ttb00000000000000000000000000001',NULL,NULL,NULL,NULL,NULL,NULL,NULL,NULL,0,0,NULL,NULL);
+INSERT INTO transactions VALUES('tx000000000000000000000000000008','eur00000000000000000000000000001','000008','2021-10-08 10:59:00','2021-10-08 10:59:00','ACME');
+INSERT INTO splits VALUES('sp8_0000000000000000000000000000','tx000000000000000000000000000008','areur000000000000000000000000001','','Invoice','n',NULL,1001,100,1001,100,'lot00000000000000000000000000008');
+INSERT INTO splits VALUES('sp8_0000000000000000000000000001','tx000000000000000000000000000008','inceur00000000000000000000000001','Price including two tax rates','','n',NULL,-794,100,-794,100,NULL);
+INSERT INTO splits VALUES('sp8_0000000000000000000000000002','tx000000000000000000000000000008','vateur00000000000000000000000001','','','n',NULL,-151,100,-151,100,NULL);
+INSERT INTO splits VALUES('sp8_0000000000000000000000000003','tx000000000000000000000000000008','vatr0000000000000000000000000001','','','n',NULL,-56,100,-56,100,NULL);
+INSERT INTO lots VALUES('lot00000000000000000000000000008','areur000000000000000000000000001',0);
+
+-- Invoice 000009: Price including tax, discounted
+INSERT INTO invoices VALUES('inv00000000000000000000000000009','000009','2021-10-09 10:59:00','2021-10-09 10:59:00','',1,'eur00000000000000000000000000001',2,'cust0000000000000000000000000001',NULL,'','tx000000000000000000000000000009','lot00000000000000000000000000009','areur000000000000000000000000001',0,NULL,0,1);
+INSERT INTO slots(obj_guid,name,slot_type,int64_val) VALUES('inv00000000000000000000000000009','credit-note',1,0);
+INSERT INTO entries VALUES('ent00000000000000000000000000009','2021-10-09 10:59:00','2021-10-09 10:59:00','Price including tax, discounted','','',1,1,'inceur00000000000000000000000001',119,1,10,1,'inv00000000000000000000000000009','PERCENT','PRETAX',1,1,'tta00000000000000000000000000001',NULL,NULL,NULL,NULL,NULL,NULL,NULL,NULL,0,0,NULL,NULL);
+INSERT INTO transactions VALUES('tx000000000000000000000000000009','eur00000000000000000000000000001','000009','2021-10-09 10:59:00','2021-10-09 10:59:00','ACME');
+INSERT INTO splits VALUES('sp9_0000000000000000000000000000','tx000000000000000000000000000009','areur000000000000000000000000001','','Invoice','n',NULL,10710,100,10710,100,'lot00000000000000000000000000009');
+INSERT INTO splits VALUES('sp9_0000000000000000000000000001','tx000000000000000000000000000009','inceur00000000000000000000000001','Price including tax, discounted','','n',NULL,-9000,100,-9000,100,NULL);
+INSERT INTO splits VALUES('sp9_0000000000000000000000000002','tx000000000000000000000000000009','vateur00000000000000000000000001','','','n',NULL,-1710,100,-1710,100,NULL);
+INSERT INTO lots VALUES('lot00000000000000000000000000009','areur000000000000000000000000001',0);
+
+-- Credit note 000010: Credit note
+INSERT INTO invoices VALUES('inv00000000000000000000000000010','000010','2021-10-10 10:59:00','2021-10-10 10:59:00','',1,'eur00000000000000000000000000001',2,'cust0000000000000000000000000001',NULL,'','tx000000000000000000000000000010','lot00000000000000000000000000010','areur000000000000000000000000001',0,NULL,0,1);
+INSERT INTO slots(obj_guid,name,slot_type,int64_val) VALUES('inv00000000000000000000000000010','credit-note',1,1);
+INSERT INTO entries VALUES('ent00000000000000000000000000010','2021-10-10 10:59:00','2021-10-10 10:59:00','Credit note','','',-2,1,'inceur00000000000000000000000001',2499,100,10,1,'inv00000000000000000000000000010','PERCENT','PRETAX',1,0,'tta00000000000000000000000000001',NULL,NULL,NULL,NULL,NULL,NULL,NULL,NULL,0,0,NULL,NULL);
+INSERT INTO transactions VALUES('tx000000000000000000000000000010','eur00000000000000000000000000001','000010','2021-10-10 10:59:00','2021-10-10 10:59:00','ACME');
+INSERT INTO splits VALUES('sp10_000000000000000000000000000','tx000000000000000000000000000010','areur000000000000000000000000001','','Invoice','n',NULL,-5353,100,-5353,100,'lot00000000000000000000000000010');
+INSERT INTO splits VALUES('sp10_000000000000000000000000001','tx000000000000000000000000000010','inceur00000000000000000000000001','Credit note','','n',NULL,4498,100,4498,100,NULL);
+INSERT INTO splits VALUES('sp10_000000000000000000000000002','tx000000000000000000000000000010','vateur00000000000000000000000001','','','n',NULL,855,100,855,100,NULL);
+INSERT INTO lots VALUES('lot00000000000000000000000000010','areur000000000000000000000000001',0);
+
+-- Invoice 000011: Yen
+INSERT INTO invoices VALUES('inv00000000000000000000000000011','000011','2021-10-11 10:59:00','2021-10-11 10:59:00','',1,'jpy00000000000000000000000000001',2,'cust0000000000000000000000000001',NULL,'','tx000000000000000000000000000011','lot00000000000000000000000000011','arjpy000000000000000000000000001',0,NULL,0,1);
+INSERT INTO slots(obj_guid,name,slot_type,int64_val) VALUES('inv00000000000000000000000000011','credit-note',1,0);
+INSERT INTO entries VALUES('ent00000000000000000000000000011','2021-10-11 10:59:00','2021-10-11 10:59:00','Yen','','',1,1,'incjpy00000000000000000000000001',1234,1,0,1,'inv00000000000000000000000000011','PERCENT','PRETAX',1,0,'ttd00000000000000000000000000001',NULL,NULL,NULL,NULL,NULL,NULL,NULL,NULL,0,0,NULL,NULL);
+INSERT INTO transactions VALUES('tx000000000000000000000000000011','jpy00000000000000000000000000001','000011','2021-10-11 10:59:00','2021-10-11 10:59:00','ACME');
+INSERT INTO splits VALUES('sp11_000000000000000000000000000','tx000000000000000000000000000011','arjpy000000000000000000000000001','','Invoice','n',NULL,1333,1,1333,1,'lot00000000000000000000000000011');
+INSERT INTO splits VALUES('sp11_000000000000000000000000001','tx000000000000000000000000000011','incjpy00000000000000000000000001','Yen','','n',NULL,-1234,1,-1234,1,NULL);
+INSERT INTO splits VALUES('sp11_000000000000000000000000002','tx000000000000000000000000000011','vatjpy00000000000000000000000001','','','n',NULL,-99,1,-99,1,NULL);
+INSERT INTO lots VALUES('lot00000000000000000000000000011','arjpy000000000000000000000000001',0);
+
+-- Invoice 000012: Dinar
+INSERT INTO invoices VALUES('inv00000000000000000000000000012','000012','2021-10-12 10:59:00','2021-10-12 10:59:00','',1,'bhd00000000000000000000000000001',2,'cust0000000000000000000000000001',NULL,'','tx000000000000000000000000000012','lot00000000000000000000000000012','arbhd000000000000000000000000001',0,NULL,0,1);
+INSERT INTO slots(obj_guid,name,slot_type,int64_val) VALUES('inv00000000000000000000000000012','credit-note',1,0);
+INSERT INTO entries VALUES('ent00000000000000000000000000012','2021-10-12 10:59:00','2021-10-12 10:59:00','Dinar','','',1,1,'incbhd00000000000000000000000001',7716,625,0,1,'inv00000000000000000000000000012','PERCENT','PRETAX',1,0,'tte00000000000000000000000000001',NULL,NULL,NULL,NULL,NULL,NULL,NULL,NULL,0,0,NULL,NULL);
+INSERT INTO transactions VALUES('tx000000000000000000000000000012','bhd00000000000000000000000000001','000012','2021-10-12 10:59:00','2021-10-12 10:59:00','ACME');
+INSERT INTO splits VALUES('sp12_000000000000000000000000000','tx000000000000000000000000000012','arbhd000000000000000000000000001','','Invoice','n',NULL,12963,1000,12963,1000,'lot00000000000000000000000000012');
+INSERT INTO splits VALUES('sp12_000000000000000000000000001','tx000000000000000000000000000012','incbhd00000000000000000000000001','Dinar','','n',NULL,-12346,1000,-12346,1000,NULL);
+INSERT INTO splits VALUES('sp12_000000000000000000000000002','tx000000000000000000000000000012','vatbhd00000000000000000000000001','','','n',NULL,-617,1000,-617,1000,NULL);
+INSERT INTO lots VALUES('lot00000000000000000000000000012','arbhd000000000000000000000000001',0);
+
+-- Bill 000013: Bill including tax
+INSERT INTO invoices VALUES('inv00000000000000000000000000013','000013','2021-10-13 10:59:00','2021-10-13 10:59:00','',1,'eur00000000000000000000000000001',4,'vend0000000000000000000000000001',NULL,'','tx000000000000000000000000000013','lot00000000000000000000000000013','ap000000000000000000000000000001',0,NULL,0,1);
+INSERT INTO slots(obj_guid,name,slot_type,int64_val) VALUES('inv00000000000000000000000000013','credit-note',1,0);
+INSERT INTO entries VALUES('ent00000000000000000000000000013','2021-10-13 10:59:00','2021-10-13 10:59:00','Bill including tax','','',2,1,NULL,NULL,NULL,NULL,NULL,NULL,NULL,NULL,0,0,NULL,'exp00000000000000000000000000001',119,2,'inv00000000000000000000000000013',1,1,'ttf00000000000000000000000000001',1,0,0,NULL,NULL);
+INSERT INTO transactions VALUES('tx000000000000000000000000000013','eur00000000000000000000000000001','000013','2021-10-13 10:59:00','2021-10-13 10:59:00','SWM');
+INSERT INTO splits VALUES('sp13_000000000000000000000000000','tx000000000000000000000000000013','ap000000000000000000000000000001','','Bill','n',NULL,-11900,100,-11900,100,'lot00000000000000000000000000013');
+INSERT INTO splits VALUES('sp13_000000000000000000000000001','tx000000000000000000000000000013','exp00000000000000000000000000001','Bill including tax','','n',NULL,10000,100,10000,100,NULL);
+INSERT INTO splits VALUES('sp13_000000000000000000000000002','tx000000000000000000000000000013','vatin000000000000000000000000001','','','n',NULL,1900,100,1900,100,NULL);
+INSERT INTO lots VALUES('lot00000000000000000000000000013','ap000000000000000000000000000001',0);
@@ -1,9 +1,6 @@
 package gnucash
 
-import (
-	"database/sql"
-	"log"
-)
+import "database/sql"
 
 type Transaction struct {
 	book *Book
@@ -27,41 +24,42 @@ const (
 	TransactionTypePayment
 )
 
+// Returns the value of the trans-txn-type slot for the type, "" if invalid
 func (t TransactionType) String() string {
 	switch t {
 	case TransactionTypeInvoice:
 		return "I"
 	case TransactionTypePayment:
 		return "P"
-	default:
-		log.Fatal("Invalid TransactionType value in String()")
 	}
 
 	return ""
 }
 
-func (t *Transaction) write() {
+func (t *Transaction) write() error {
 	query := `INSERT OR REPLACE INTO "transactions" ("guid", "currency_guid",
 			"num", "post_date", "enter_date", "description")
 		VALUES (:guid, :currency_guid, :num, :post_date, :enter_date,
 			:description)`
 	_, err := t.book.DB.NamedExec(query, t.DbTransaction)
-	if err != nil {
-		log.Fatal(err)
-	}
+	return dbError(err, "writing transaction %s", t.Guid)
 }
 
-func (t *Transaction) GetCurrency() *Commodity {
+func (t *Transaction) GetCurrency() (*Commodity, error) {
 	c := t.book.GetCommodityByGUID(t.CurrencyGuid)
 	if c == nil {
-		log.Fatalf("Currency %s for transaction %s not found\n",
+		return nil, newError(ErrCorruptBook, "currency %s of transaction %s not found",
 			t.CurrencyGuid, t.Guid)
 	}
 
-	return c
+	return c, nil
 }
 
-func (t *Transaction) AddSplit(s *Split) {
+func (t *Transaction) AddSplit(s *Split) error {
+	if err := t.book.checkComplete("adding a split to transaction " + t.Guid); err != nil {
+		return err
+	}
+
 	if s.Guid == "" {
 		s.Guid = NewGuid()
 	}
@@ -69,13 +67,23 @@ func (t *Transaction) AddSplit(s *Split) {
 	s.book = t.book
 	s.TxGuid = t.Guid
 	s.AccountGuid = s.Account.Guid
-	s.Write()
+	if err := s.Write(); err != nil {
+		return err
+	}
 	t.book.splits = append(t.book.splits, s)
+	t.book.splitsByGuid[s.Guid] = s
 	t.Splits = append(t.Splits, s)
+	s.Account.Splits = append(s.Account.Splits, s)
 	s.Transaction = t
+
+	return nil
 }
 
-func (t *Transaction) SetType(tp TransactionType) {
+func (t *Transaction) SetType(tp TransactionType) error {
+	if tp.String() == "" {
+		return newError(ErrUnsupported, "transaction type %d", int(tp))
+	}
+
 	slot := t.book.getSlotForObjByName(t.Guid, "trans-txn-type")
 	if slot == nil {
 		// If slot doesn't exist, create it
@@ -87,14 +95,25 @@ func (t *Transaction) SetType(tp TransactionType) {
 				StringVal: sql.NullString{tp.String(), true},
 			},
 		}
-		t.book.AddSlot(slot)
-	} else {
-		slot.StringVal = sql.NullString{tp.String(), true}
-		slot.Write()
+		return t.book.AddSlot(slot)
 	}
+
+	slot.StringVal = sql.NullString{tp.String(), true}
+	return slot.Write()
+}
+
+// Sets the due date of the transaction (used for posted invoices)
+func (t *Transaction) SetDateDue(date string) error {
+	_, err := t.book.setSlot(t.Guid, "trans-date-due", &Slot{
+		DbSlot: DbSlot{
+			SlotType:    int(SlotTypeTime64),
+			TimespecVal: sql.NullString{date, true},
+		},
+	})
+	return err
 }
 
-func (t *Transaction) SetReadOnly(ro bool) {
+func (t *Transaction) SetReadOnly(ro bool) error {
 	slot := t.book.getSlotForObjByName(t.Guid, "trans-read-only")
 	if ro {
 		if slot == nil {
@@ -106,18 +125,63 @@ func (t *Transaction) SetReadOnly(ro bool) {
 					StringVal: sql.NullString{"Generated from an invoice. Try unposting the invoice.", true},
 				},
 			}
-			t.book.AddSlot(slot)
-		} else {
-			if slot.SlotType != int(SlotTypeString) {
-				log.Fatal("transaction-read-only slot found, but it's not of string type")
-			}
+			return t.book.AddSlot(slot)
+		}
+
+		if slot.SlotType != int(SlotTypeString) {
+			return newError(ErrCorruptBook, "trans-read-only slot of transaction %s is not of string type",
+				t.Guid)
+		}
+
+		slot.StringVal = sql.NullString{"Generated from an invoice. Try unposting the invoice.", true}
+		return slot.Write()
+	}
 
-			slot.StringVal = sql.NullString{"Generated from an invoice. Try unposting the invoice.", true}
-			slot.Write()
+	if slot != nil {
+		return t.book.RemoveSlot(slot)
+	}
+
+	return nil
+}
+
+// Deletes transaction including all of its splits and slots. Transactions
+// created by posting an invoice can only be removed by unposting the invoice.
+func (t *Transaction) Delete() error {
+	if err := t.book.checkComplete("deleting transaction " + t.Guid); err != nil {
+		return err
+	}
+
+	if i := t.book.invoicesByPostTxn[t.Guid]; i != nil {
+		return newError(ErrConstraint, "transaction %s belongs to invoice %s, unpost the invoice instead",
+			t.Guid, i.Id)
+	}
+
+	return t.remove()
+}
+
+func (t *Transaction) remove() error {
+	for len(t.Splits) > 0 {
+		if err := t.Splits[0].remove(); err != nil {
+			return err
 		}
-	} else {
-		if slot != nil {
-			t.book.RemoveSlot(slot)
+	}
+
+	_, err := t.book.DB.Exec(`DELETE FROM "transactions" WHERE "guid"=?`, t.Guid)
+	if err != nil {
+		return dbError(err, "deleting transaction %s", t.Guid)
+	}
+
+	if err := t.book.removeSlotsForObj(t.Guid); err != nil {
+		return err
+	}
+
+	delete(t.book.transactionsByGuid, t.Guid)
+	for i, x := range t.book.transactions {
+		if x == t {
+			t.book.transactions = append(t.book.transactions[:i], t.book.transactions[i+1:]...)
+			break
 		}
 	}
+
+	return nil
 }
@@ -16,7 +16,40 @@ func GetCurrentTimeString() string {
 	return time.Now().Format("2006-01-02 15:04:05")
 }
 
+// Returns the date of t at 10:59:00, which GnuCash uses as time of day for
+// values that are only dates (e.g. transaction post dates)
+func GetDateString(t time.Time) string {
+	return t.Format("2006-01-02") + " 10:59:00"
+}
+
 func GncRationalToString(num int64, denom int64) string {
 	rat := big.NewRat(num, denom)
 	return rat.FloatString(2)
 }
+
+// Rounds r to a multiple of 1/denom, rounding halves away from zero
+func roundRat(r *big.Rat, denom int64) *big.Rat {
+	n := new(big.Int).Mul(r.Num(), big.NewInt(denom))
+	d := r.Denom()
+
+	q, m := new(big.Int).QuoRem(n, d, new(big.Int))
+	m.Abs(m)
+	m.Mul(m, big.NewInt(2))
+	if m.Cmp(d) >= 0 {
+		if n.Sign() < 0 {
+			q.Sub(q, big.NewInt(1))
+		} else {
+			q.Add(q, big.NewInt(1))
+		}
+	}
+
+	return new(big.Rat).SetFrac(q, big.NewInt(denom))
+}
+
+func boolToInt32(b bool) int32 {
+	if b {
+		return 1
+	}
+
+	return 0
+}
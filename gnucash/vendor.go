@@ -1,9 +1,20 @@
 package gnucash
 
-import "database/sql"
+import (
+	"database/sql"
+	"sort"
+	"strings"
+)
+
+// Frame holding alternative names of a vendor, e.g. the name a config file is
+// named after
+const vendorAliasesSlot = "aliases"
 
 type Vendor struct {
+	book *Book
 	DbVendor
+
+	indexed indexedKeys // keys of the book's indexes, see index
 }
 
 type DbVendor struct {
@@ -26,3 +37,110 @@ type DbVendor struct {
 	TaxInc      sql.NullString `db:"tax_inc"`
 	TaxTable    sql.NullString `db:"tax_table"`
 }
+
+func (v *Vendor) Write() error {
+	query := `INSERT OR REPLACE INTO "vendors" ("guid", "name", "id", "notes",
+			"currency", "active", "tax_override", "addr_name", "addr_addr1",
+			"addr_addr2", "addr_addr3", "addr_addr4", "addr_phone", "addr_fax",
+			"addr_email", "terms", "tax_inc", "tax_table")
+		VALUES (:guid, :name, :id, :notes, :currency, :active, :tax_override,
+			:addr_name, :addr_addr1, :addr_addr2, :addr_addr3, :addr_addr4,
+			:addr_phone, :addr_fax, :addr_email, :terms, :tax_inc, :tax_table)`
+
+	_, err := v.book.DB.NamedExec(query, v.DbVendor)
+	if err != nil {
+		return dbError(err, "writing vendor %s", v.Name)
+	}
+
+	v.index()
+	return nil
+}
+
+// Updates the book's indexes by name and ID
+func (v *Vendor) index() {
+	v.book.vendorsByName.update(v.Guid, v.indexed.ok, v.indexed.name, v.Name)
+	v.book.vendorsById.update(v.Guid, v.indexed.ok, v.indexed.id, v.Id)
+	v.indexed = indexedKeys{true, v.Name, v.Id}
+}
+
+// Returns the address of the vendor
+func (v *Vendor) GetAddress() Address {
+	return Address{v.AddrName.String, v.AddrAddr1.String, v.AddrAddr2.String,
+		v.AddrAddr3.String, v.AddrAddr4.String, v.AddrPhone.String,
+		v.AddrFax.String, v.AddrEmail.String}
+}
+
+// Sets the address of the vendor (call Write to save it)
+func (v *Vendor) SetAddress(a Address) {
+	v.AddrName = sql.NullString{a.Name, true}
+	v.AddrAddr1 = sql.NullString{a.Addr1, true}
+	v.AddrAddr2 = sql.NullString{a.Addr2, true}
+	v.AddrAddr3 = sql.NullString{a.Addr3, true}
+	v.AddrAddr4 = sql.NullString{a.Addr4, true}
+	v.AddrPhone = sql.NullString{a.Phone, true}
+	v.AddrFax = sql.NullString{a.Fax, true}
+	v.AddrEmail = sql.NullString{a.Email, true}
+}
+
+// Sets the default billing terms (GUID, "" for none) of the vendor (call Write
+// to save them)
+func (v *Vendor) SetTermsGuid(guid string) {
+	v.Terms = sql.NullString{guid, guid != ""}
+}
+
+// Returns the default billing terms of the vendor, nil if it has none
+func (v *Vendor) GetTerms() *BillTerm {
+	if !v.Terms.Valid {
+		return nil
+	}
+
+	return v.book.GetBillTermByGUID(v.Terms.String)
+}
+
+// Returns the aliases of the vendor, sorted by name
+func (v *Vendor) GetAliases() []string {
+	aliases := []string{}
+
+	frame := v.book.GetSlot(v.Guid, vendorAliasesSlot)
+	if frame == nil {
+		return aliases
+	}
+
+	for _, s := range frame.GetChildren() {
+		aliases = append(aliases, s.StringVal.String)
+	}
+	sort.Strings(aliases)
+
+	return aliases
+}
+
+// Returns true if alias is one of the aliases of the vendor
+func (v *Vendor) HasAlias(alias string) bool {
+	return v.book.GetSlot(v.Guid, vendorAliasesSlot+"/"+alias) != nil
+}
+
+// Adds an alternative name the vendor can be found by (see
+// Book.GetVendorByAlias). Aliases must not contain '/'.
+func (v *Vendor) AddAlias(alias string) error {
+	if alias == "" || strings.Contains(alias, "/") {
+		return newError(ErrConstraint, "invalid alias '%s' of vendor %s", alias, v.Name)
+	}
+
+	_, err := v.book.SetSlotString(v.Guid, vendorAliasesSlot+"/"+alias, alias)
+	return err
+}
+
+// Removes an alias of the vendor (if it exists)
+func (v *Vendor) RemoveAlias(alias string) error {
+	if err := v.book.DeleteSlot(v.Guid, vendorAliasesSlot+"/"+alias); err != nil {
+		return err
+	}
+
+	// Remove the frame together with the last alias
+	if frame := v.book.GetSlot(v.Guid, vendorAliasesSlot); frame != nil &&
+		len(frame.GetChildren()) == 0 {
+		return v.book.DeleteSlot(v.Guid, vendorAliasesSlot)
+	}
+
+	return nil
+}
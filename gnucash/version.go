@@ -0,0 +1,105 @@
+package gnucash
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Books this package supports, for error messages
+const SupportedVersions = "GnuCash 3.0 to 5.x"
+
+// Versions of the tables this package reads or writes, as written by GnuCash
+// 3.0 and later. GnuCash 2.x wrote lower versions of some of them (e.g. with
+// timestamps formatted as 20211015105900 instead of 2021-10-15 10:59:00),
+// newer ones may have additional columns.
+var tableVersions = map[string]int{
+	"books":            1,
+	"commodities":      1,
+	"accounts":         1,
+	"transactions":     4,
+	"splits":           5,
+	"slots":            4,
+	"lots":             2,
+	"billterms":        2,
+	"customers":        2,
+	"employees":        2,
+	"entries":          4,
+	"invoices":         4,
+	"jobs":             1,
+	"taxtables":        2,
+	"taxtable_entries": 3,
+	"vendors":          1,
+}
+
+type DbVersion struct {
+	TableName    string `db:"table_name"`
+	TableVersion int    `db:"table_version"`
+}
+
+// Reads the versions table and checks that the book was written by a
+// supported version of GnuCash. Returns the versions by table name, including
+// the version of GnuCash itself as "Gnucash".
+func readVersions(db *sqlx.DB) (map[string]int, error) {
+	vs := []DbVersion{}
+	err := db.Select(&vs, "SELECT * FROM versions")
+	if err != nil {
+		return nil, newError(ErrUnsupported, "reading versions (not a GnuCash book?): %v, supported are %s",
+			err, SupportedVersions)
+	}
+
+	versions := make(map[string]int)
+	for _, v := range vs {
+		versions[v.TableName] = v.TableVersion
+	}
+
+	gnucash, ok := versions["Gnucash"]
+	if !ok {
+		return nil, newError(ErrCorruptBook, "version of GnuCash not found in versions")
+	}
+
+	if gnucash < 3000000 {
+		return nil, newError(ErrUnsupported,
+			"book of GnuCash %s uses an older format (e.g. of timestamps), save it with GnuCash 3.0 or later first (supported are %s)",
+			formatVersion(gnucash), SupportedVersions)
+	}
+
+	tables := []string{}
+	for table := range tableVersions {
+		tables = append(tables, table)
+	}
+	sort.Strings(tables)
+
+	for _, table := range tables {
+		if have, want := versions[table], tableVersions[table]; have != want {
+			return nil, newError(ErrUnsupported,
+				"table %s of book of GnuCash %s has version %d instead of %d (supported are %s)",
+				table, formatVersion(gnucash), have, want, SupportedVersions)
+		}
+	}
+
+	return versions, nil
+}
+
+// Formats a GnuCash version as stored in the versions table. GnuCash 3.0 and
+// later store major*1000000+minor (e.g. 4000008 for "4.8"), 2.x stored
+// major*1000000+minor*10000+micro*100 (e.g. 2062100 for "2.6.21").
+func formatVersion(v int) string {
+	if v < 3000000 {
+		return fmt.Sprintf("%d.%d.%d", v/1000000, v/10000%100, v/100%100)
+	}
+
+	return fmt.Sprintf("%d.%d", v/1000000, v%1000000)
+}
+
+// Returns the version of GnuCash that last wrote the book (e.g. "4.8")
+func (b *Book) GetGnuCashVersion() string {
+	return formatVersion(b.versions["Gnucash"])
+}
+
+// Returns the version of table (e.g. "splits"), 0 if the book has no such
+// table
+func (b *Book) GetTableVersion(table string) int {
+	return b.versions[table]
+}
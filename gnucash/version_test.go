@@ -0,0 +1,103 @@
+package gnucash
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+)
+
+func TestFormatVersion(t *testing.T) {
+	tests := []struct {
+		v    int
+		want string
+	}{
+		{2062100, "2.6.21"},
+		{2060600, "2.6.6"},
+		{3000000, "3.0"},
+		{3000011, "3.11"},
+		{4000008, "4.8"},
+		{4000014, "4.14"},
+		{5000004, "5.4"},
+	}
+
+	for _, tc := range tests {
+		if got := formatVersion(tc.v); got != tc.want {
+			t.Errorf("formatVersion(%d) = %s, want %s", tc.v, got, tc.want)
+		}
+	}
+}
+
+func TestReadVersions(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string // run on the test book before opening it
+		err   error
+		msg   string
+	}{
+		{"current book", "", nil, ""},
+		{"newer book", `UPDATE versions SET table_version=5000004 WHERE table_name='Gnucash'`, nil, ""},
+		{"2.6 book", `UPDATE versions SET table_version=2062100 WHERE table_name='Gnucash'`,
+			ErrUnsupported, "book of GnuCash 2.6.21 uses an older format"},
+		{"table version mismatch", `UPDATE versions SET table_version=4 WHERE table_name='splits'`,
+			ErrUnsupported, "table splits of book of GnuCash 4.8 has version 4 instead of 5"},
+		{"missing table version", `DELETE FROM versions WHERE table_name='lots'`,
+			ErrUnsupported, "table lots of book of GnuCash 4.8 has version 0 instead of 2"},
+		{"missing GnuCash version", `DELETE FROM versions WHERE table_name='Gnucash'`,
+			ErrCorruptBook, "version of GnuCash not found"},
+		{"missing versions table", `DROP TABLE versions`,
+			ErrUnsupported, "reading versions (not a GnuCash book?)"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			path := createTestBook(t, "book.sql")
+			if tc.query != "" {
+				db, err := sqlx.Open("sqlite3", path)
+				if err != nil {
+					t.Fatal(err)
+				}
+				_, err = db.Exec(tc.query)
+				db.Close()
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			book, err := OpenBookFromSQLite(path)
+			if tc.err != nil {
+				if !errors.Is(err, tc.err) || !strings.Contains(err.Error(), tc.msg) {
+					t.Errorf("error %v, want %v: %s", err, tc.err, tc.msg)
+				}
+				if book != nil {
+					book.Close()
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer book.Close()
+
+			if got := book.GetTableVersion("splits"); got != 5 {
+				t.Errorf("splits version %d, want 5", got)
+			}
+			if got := book.GetTableVersion("budgets"); got != 1 {
+				t.Errorf("budgets version %d, want 1", got)
+			}
+			if got := book.GetTableVersion("nonexistent"); got != 0 {
+				t.Errorf("nonexistent table version %d, want 0", got)
+			}
+		})
+	}
+}
+
+func TestGetGnuCashVersion(t *testing.T) {
+	book := openTestBook(t, "book.sql")
+
+	if v := book.GetGnuCashVersion(); v != "4.8" {
+		t.Errorf("GnuCash version %s, want 4.8", v)
+	}
+}
@@ -2,12 +2,15 @@ module bvorhofer.com/matchmaker
 
 go 1.17
 
-require github.com/spf13/cobra v1.3.0
+require (
+	github.com/google/uuid v1.3.0
+	github.com/jmoiron/sqlx v1.3.4
+	github.com/mattn/go-sqlite3 v1.14.12
+	github.com/spf13/cobra v1.3.0
+	gopkg.in/yaml.v2 v2.4.0
+)
 
 require (
-	github.com/google/uuid v1.3.0 // indirect
 	github.com/inconshreveable/mousetrap v1.0.0 // indirect
-	github.com/jmoiron/sqlx v1.3.4 // indirect
-	github.com/mattn/go-sqlite3 v1.14.12 // indirect
 	github.com/spf13/pflag v1.0.5 // indirect
 )